@@ -0,0 +1,154 @@
+package callback
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Jitter defines how randomness is applied to backoff delays, so that many senders
+// retrying against the same endpoint do not do so in lockstep.
+type Jitter string
+
+var (
+	// NoJitter uses the exponential delay as is.
+	NoJitter Jitter = "none"
+
+	// FullJitter picks a random delay between zero and the exponential delay.
+	FullJitter Jitter = "full"
+
+	// EqualJitter keeps half of the exponential delay and randomizes the other half.
+	EqualJitter Jitter = "equal"
+
+	// DecorrelatedJitter picks a random delay between the initial delay and
+	// the previous delay multiplied by the multiplier.
+	DecorrelatedJitter Jitter = "decorrelated"
+)
+
+// Backoff configures exponential backoff between retry attempts of a message
+// and for the period an endpoint is blocked after repeated failures.
+type Backoff struct {
+
+	// Initial is the delay before the first retry of a message.
+	// Default value: time.Millisecond * 100
+	Initial time.Duration
+
+	// Multiplier is the factor by which the delay grows after every attempt.
+	// Default value: 2
+	Multiplier float64
+
+	// Max caps the delay between retries. Block periods are capped at the larger of Max and RetryTimeout.
+	// Default value: time.Second * 30
+	Max time.Duration
+
+	// Jitter defines how randomness is applied to the delay.
+	// Default value: FullJitter
+	Jitter Jitter
+}
+
+// defaultBackoff initializes default values for Backoff fields that are not set.
+func defaultBackoff(b *Backoff) *Backoff {
+
+	// Set default initial delay to 100 milliseconds if none is specified
+	if b.Initial == 0 {
+		b.Initial = time.Millisecond * 100
+	}
+
+	// Set default multiplier to 2 if none is specified
+	if b.Multiplier == 0 {
+		b.Multiplier = 2
+	}
+
+	// Set default maximum delay to 30 seconds if none is specified
+	if b.Max == 0 {
+		b.Max = time.Second * 30
+	}
+
+	// Set default jitter to FullJitter if none is specified
+	if b.Jitter == "" {
+		b.Jitter = FullJitter
+	}
+
+	return b
+}
+
+// duration calculates the delay for the n-th consecutive attempt (starting at zero),
+// growing from base. prev is the previous delay and is used by DecorrelatedJitter.
+func (b *Backoff) duration(base time.Duration, n int, prev time.Duration) time.Duration {
+	if b.Jitter == DecorrelatedJitter {
+		return b.decorrelated(base, prev)
+	}
+	return b.jitter(b.exponential(base, n))
+}
+
+// floorDuration is like duration, but never returns less than base:
+// the jitter is only applied to the growth above base.
+func (b *Backoff) floorDuration(base time.Duration, n int, prev time.Duration) time.Duration {
+	if b.Jitter == DecorrelatedJitter {
+		// Decorrelated delays never go below base.
+		return b.decorrelated(base, prev)
+	}
+	return base + b.jitter(b.exponential(base, n)-base)
+}
+
+// exponential returns the delay for the n-th attempt without jitter, growing from base.
+func (b *Backoff) exponential(base time.Duration, n int) time.Duration {
+	// The delay never exceeds the ceiling, but the ceiling never cuts below base.
+	ceiling := max(b.Max, base)
+
+	// Exponential growth capped at the ceiling.
+	return time.Duration(min(float64(base)*math.Pow(b.Multiplier, float64(n)), float64(ceiling)))
+}
+
+// decorrelated returns a random delay between base and the previous delay multiplied by the multiplier.
+func (b *Backoff) decorrelated(base, prev time.Duration) time.Duration {
+	ceiling := max(b.Max, base)
+
+	// Grow from the previous delay instead of the attempt number.
+	upper := max(min(time.Duration(float64(max(prev, base))*b.Multiplier), ceiling), base)
+	return base + time.Duration(rand.Int64N(int64(upper-base)+1))
+}
+
+// jitter applies the FullJitter or EqualJitter randomness to delay.
+func (b *Backoff) jitter(delay time.Duration) time.Duration {
+	switch b.Jitter {
+	case FullJitter:
+		return time.Duration(rand.Int64N(int64(delay) + 1))
+	case EqualJitter:
+		half := delay / 2
+		return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+	default:
+		return delay
+	}
+}
+
+// retryDelay returns the delay before the next retry of a failed message
+// and remembers it for the following attempt. Without a backoff policy, retries are immediate.
+func (c *Callback) retryDelay(msg *message) time.Duration {
+	if c.backoff == nil {
+		return 0
+	}
+
+	msg.delay = c.backoff.duration(c.backoff.Initial, len(msg.attempts)-1, msg.delay)
+	return msg.delay
+}
+
+// blockDuration returns how long an endpoint is blocked for the n-th consecutive time
+// (starting at zero). It is never shorter than RetryTimeout, so that jitter does not
+// unblock a failing endpoint early. Without a backoff policy, it is always RetryTimeout.
+func (c *Callback) blockDuration(n int, prev time.Duration) time.Duration {
+	if c.backoff == nil {
+		return c.retryTimeout
+	}
+
+	return c.backoff.floorDuration(c.retryTimeout, n, prev)
+}
+
+// after calls f after delay, or immediately in the calling goroutine if there is no delay.
+func after(delay time.Duration, f func()) {
+	if delay <= 0 {
+		f()
+		return
+	}
+	time.AfterFunc(delay, f)
+}
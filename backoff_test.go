@@ -0,0 +1,119 @@
+package callback
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDefaultBackoff tests that unset Backoff fields receive default values.
+func TestDefaultBackoff(t *testing.T) {
+	got := defaultOptions(&Options{Backoff: &Backoff{Initial: time.Second}}).Backoff
+	expected := Backoff{Initial: time.Second, Multiplier: 2, Max: time.Second * 30, Jitter: FullJitter}
+	if *got != expected {
+		t.Errorf("defaultOptions() Backoff = %+v, want %+v", *got, expected)
+	}
+
+	// Without a policy no backoff is configured.
+	if defaultOptions(&Options{}).Backoff != nil {
+		t.Error("expected no backoff by default")
+	}
+}
+
+// TestBackoff_Duration tests the delay ranges for every jitter mode.
+func TestBackoff_Duration(t *testing.T) {
+	base := time.Millisecond * 100
+
+	t.Run("NoJitter", func(t *testing.T) {
+		b := &Backoff{Multiplier: 2, Max: time.Millisecond * 500, Jitter: NoJitter}
+		expected := []time.Duration{100, 200, 400, 500, 500}
+		for n, want := range expected {
+			if got := b.duration(base, n, 0); got != want*time.Millisecond {
+				t.Errorf("duration(%d) = %v, want %v", n, got, want*time.Millisecond)
+			}
+		}
+	})
+
+	t.Run("FullJitter", func(t *testing.T) {
+		b := &Backoff{Multiplier: 2, Max: time.Second, Jitter: FullJitter}
+		for i := 0; i < 100; i++ {
+			if got := b.duration(base, 2, 0); got < 0 || got > time.Millisecond*400 {
+				t.Fatalf("duration out of range [0, 400ms]: %v", got)
+			}
+		}
+	})
+
+	t.Run("EqualJitter", func(t *testing.T) {
+		b := &Backoff{Multiplier: 2, Max: time.Second, Jitter: EqualJitter}
+		for i := 0; i < 100; i++ {
+			if got := b.duration(base, 2, 0); got < time.Millisecond*200 || got > time.Millisecond*400 {
+				t.Fatalf("duration out of range [200ms, 400ms]: %v", got)
+			}
+		}
+	})
+
+	t.Run("DecorrelatedJitter", func(t *testing.T) {
+		b := &Backoff{Multiplier: 3, Max: time.Second, Jitter: DecorrelatedJitter}
+		for i := 0; i < 100; i++ {
+			if got := b.duration(base, 0, time.Millisecond*200); got < base || got > time.Millisecond*600 {
+				t.Fatalf("duration out of range [100ms, 600ms]: %v", got)
+			}
+			if got := b.duration(base, 0, time.Second); got < base || got > time.Second {
+				t.Fatalf("duration out of range [100ms, 1s]: %v", got)
+			}
+		}
+	})
+
+	t.Run("CeilingBelowBase", func(t *testing.T) {
+		b := &Backoff{Multiplier: 2, Max: time.Millisecond, Jitter: NoJitter}
+		if got := b.duration(base, 3, 0); got != base {
+			t.Errorf("duration = %v, want %v", got, base)
+		}
+	})
+}
+
+// TestWorker_BlockBackoff tests that the block period grows with consecutive blocks
+// and is reset after a success.
+func TestWorker_BlockBackoff(t *testing.T) {
+	clb := &Callback{
+		retryLimit:   0,
+		retryTimeout: time.Second,
+		retryWindow:  time.Minute,
+		backoff:      &Backoff{Multiplier: 2, Max: time.Minute, Jitter: NoJitter},
+	}
+	worker := &Worker{callback: clb}
+
+	for _, want := range []time.Duration{time.Second, time.Second * 2, time.Second * 4} {
+		// Let the previous block expire so the next error blocks again.
+		worker.blockedUntil = time.Time{}
+		worker.Inc()
+		if worker.blockedFor != want {
+			t.Errorf("expected block period %v, got %v", want, worker.blockedFor)
+		}
+	}
+
+	worker.Reset()
+	worker.Inc()
+	if worker.blockedFor != time.Second {
+		t.Errorf("expected block period to reset to %v, got %v", time.Second, worker.blockedFor)
+	}
+}
+
+// TestCallback_BlockDurationJitter tests that a jittered block period is never shorter than RetryTimeout.
+func TestCallback_BlockDurationJitter(t *testing.T) {
+	for _, jitter := range []Jitter{FullJitter, EqualJitter, DecorrelatedJitter} {
+		t.Run(string(jitter), func(t *testing.T) {
+			clb := &Callback{
+				retryTimeout: time.Second,
+				backoff:      &Backoff{Multiplier: 2, Max: time.Minute, Jitter: jitter},
+			}
+			for i := 0; i < 100; i++ {
+				for n := 0; n < 3; n++ {
+					got := clb.blockDuration(n, time.Second)
+					if got < time.Second || got > time.Second*4 {
+						t.Fatalf("blockDuration(%d) out of range [1s, 4s]: %v", n, got)
+					}
+				}
+			}
+		})
+	}
+}
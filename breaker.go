@@ -0,0 +1,147 @@
+package callback
+
+import "time"
+
+// State is the state of an endpoint reported in an Event.
+type State string
+
+var (
+	// Closed is the circuit breaker state in which messages flow normally.
+	Closed State = "closed"
+
+	// Open is the circuit breaker state in which the endpoint is blocked after too many errors.
+	Open State = "open"
+
+	// HalfOpen is the circuit breaker state after the open period, in which only a limited
+	// number of probe messages is let through until enough of them succeed in a row.
+	HalfOpen State = "half_open"
+)
+
+// halfOpen moves an open worker whose block period has passed to the half-open state.
+// It must be called with w.mu held.
+func (w *Worker) halfOpen(now time.Time) {
+	if w.state == Open && now.After(w.blockedUntil) {
+		w.setState(HalfOpen)
+		w.successes = 0
+	}
+}
+
+// open blocks the worker for the next block period, which grows with every consecutive
+// block if a backoff policy is configured. It must be called with w.mu held.
+func (w *Worker) open(now time.Time) {
+	w.blockedFor = w.callback.blockDuration(w.blocks, w.blockedFor)
+	w.blockedUntil = now.Add(w.blockedFor)
+	w.blocks++
+	w.successes = 0
+	w.setState(Open)
+}
+
+// close returns the worker to the closed state and clears its error history.
+// It must be called with w.mu held.
+func (w *Worker) close() {
+	w.errorTimestamps = w.errorTimestamps[:0]
+	w.blockedUntil = time.Time{}
+	w.blocks = 0
+	w.blockedFor = 0
+	w.successes = 0
+	w.setState(Closed)
+}
+
+// setState changes the breaker state and records the transition, which is reported
+// by flush once w.mu is released. It must be called with w.mu held.
+func (w *Worker) setState(to State) {
+	from := w.state
+	if from == "" {
+		from = Closed
+	}
+	w.state = to
+
+	if from != to {
+		w.transitions = append(w.transitions, Event{
+			Point: w.point,
+			Type:  BreakerEvent,
+			From:  from,
+			To:    to,
+			Time:  time.Now(),
+		})
+	}
+}
+
+// flush reports the recorded state transitions. It must be called without w.mu held.
+func (w *Worker) flush() {
+	w.mu.Lock()
+	transitions := w.transitions
+	w.transitions = nil
+	w.mu.Unlock()
+
+	for _, event := range transitions {
+		w.callback.event(event)
+	}
+}
+
+// succeed records a successful delivery. In the half-open state the worker closes
+// after SuccessThreshold consecutive successes; in the closed state the error count is cleared.
+func (w *Worker) succeed() {
+	defer w.flush()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.state {
+	case HalfOpen:
+		w.successes++
+		if w.successes >= w.callback.successThreshold {
+			w.close()
+		}
+	case Open:
+		// A message queued before the breaker opened does not close it.
+	default:
+		w.errorTimestamps = w.errorTimestamps[:0]
+	}
+}
+
+// track marks a message enqueued while the worker is half-open as a probe.
+func (w *Worker) track(msg *message) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg.probe = w.state == HalfOpen
+	if msg.probe {
+		w.probes++
+	}
+}
+
+// reserve reports whether the worker accepts a message, like available, and takes a probe
+// slot while the worker is half-open, setting probe. The check and the reservation share
+// one lock, so that concurrent senders cannot exceed HalfOpenProbes.
+func (w *Worker) reserve(probe *bool) bool {
+	defer w.flush() // Report state transitions once the mutex is released.
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.accepts(time.Now()) {
+		return false
+	}
+	if w.state == HalfOpen {
+		*probe = true
+		w.probes++
+	}
+	return true
+}
+
+// release frees the probe slot taken by a message once its attempt has completed.
+func (w *Worker) release(msg *message) {
+	w.free(&msg.probe)
+}
+
+// free frees the probe slot recorded in probe, if any.
+func (w *Worker) free(probe *bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if *probe {
+		*probe = false
+		w.probes--
+	}
+}
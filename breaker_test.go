@@ -0,0 +1,167 @@
+package callback
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBreakerWorker creates a worker whose breaker opens on the first error for period.
+func newBreakerWorker(period time.Duration) (*Worker, chan Event) {
+	events := make(chan Event, 10)
+	clb := &Callback{
+		retryLimit:       0,
+		retryTimeout:     period,
+		retryWindow:      time.Minute,
+		halfOpenProbes:   1,
+		successThreshold: 2,
+		eventChannel:     events,
+	}
+	return &Worker{callback: clb, point: "a", state: Closed}, events
+}
+
+// expectTransitions checks the reported state transitions.
+func expectTransitions(t *testing.T, events chan Event, expected ...State) {
+	t.Helper()
+
+	for i := 0; i+1 < len(expected); i++ {
+		select {
+		case event := <-events:
+			if event.Type != BreakerEvent || event.Point != "a" || event.From != expected[i] || event.To != expected[i+1] {
+				t.Fatalf("expected transition %s -> %s, got %+v", expected[i], expected[i+1], event)
+			}
+		default:
+			t.Fatalf("expected transition %s -> %s, got none", expected[i], expected[i+1])
+		}
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected transition %+v", event)
+	default:
+	}
+}
+
+// TestBreaker_HalfOpenProbes tests that a half-open worker lets a limited number of probes
+// through and closes only after consecutive successes.
+func TestBreaker_HalfOpenProbes(t *testing.T) {
+	worker, events := newBreakerWorker(time.Millisecond * 20)
+
+	// The first error opens the breaker.
+	if !worker.Inc() {
+		t.Fatal("expected the worker to be blocked")
+	}
+	if worker.available() {
+		t.Fatal("expected an open worker to be unavailable")
+	}
+	expectTransitions(t, events, Closed, Open)
+
+	// After the open period the worker becomes half-open and accepts one probe.
+	time.Sleep(time.Millisecond * 30)
+	if !worker.available() {
+		t.Fatal("expected a half-open worker to accept a probe")
+	}
+	expectTransitions(t, events, Open, HalfOpen)
+
+	probe := &message{}
+	worker.track(probe)
+	if worker.available() {
+		t.Fatal("expected a half-open worker with a probe in flight to be unavailable")
+	}
+
+	// The first successful probe does not close the breaker yet.
+	worker.release(probe)
+	worker.succeed()
+	if worker.State() != HalfOpen {
+		t.Fatalf("expected half-open state, got %s", worker.State())
+	}
+
+	// The second consecutive success closes it.
+	worker.track(probe)
+	worker.release(probe)
+	worker.succeed()
+	if worker.State() != Closed {
+		t.Fatalf("expected closed state, got %s", worker.State())
+	}
+	expectTransitions(t, events, HalfOpen, Closed)
+}
+
+// TestBreaker_ReserveProbes tests that concurrent senders cannot take more probe slots
+// of a half-open worker than HalfOpenProbes.
+func TestBreaker_ReserveProbes(t *testing.T) {
+	worker, _ := newBreakerWorker(time.Millisecond * 20)
+	worker.Inc()
+	time.Sleep(time.Millisecond * 30)
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var probe bool
+			if worker.available() && worker.reserve(&probe) {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := reserved.Load(); n != 1 {
+		t.Fatalf("expected one probe slot to be reserved, got %d", n)
+	}
+
+	// A hedge cannot take the slot of the probe in flight either.
+	clb := worker.callback
+	clb.endPoints = []*Worker{worker}
+	var probe bool
+	if hedge := clb.hedgeTarget(&message{}, nil, &probe); hedge != nil {
+		t.Errorf("expected no hedge target, got %s", hedge.point)
+	}
+}
+
+// TestBreaker_FailedProbe tests that a failed probe opens the breaker again.
+func TestBreaker_FailedProbe(t *testing.T) {
+	worker, events := newBreakerWorker(time.Millisecond * 20)
+
+	worker.Inc()
+	time.Sleep(time.Millisecond * 30)
+	worker.available()
+
+	if !worker.Inc() {
+		t.Fatal("expected a failed probe to block the worker")
+	}
+	if worker.State() != Open {
+		t.Fatalf("expected open state, got %s", worker.State())
+	}
+	expectTransitions(t, events, Closed, Open, HalfOpen, Open)
+}
+
+// TestBreaker_OnEvent tests that state transitions are passed to the OnEvent handler.
+func TestBreaker_OnEvent(t *testing.T) {
+	clb := New(&Options{
+		Transport:  &flakyTransport{failures: map[string]int{"a": -1}},
+		RetryLimit: 1,
+		EndPoints:  []string{"a"},
+	})
+	defer clb.Close()
+
+	events := make(chan *Event, 10)
+	clb.OnEvent(func(event *Event) {
+		events <- event
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.From != Closed || event.To != Open {
+			t.Errorf("expected closed -> open, got %+v", event)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for the breaker event")
+	}
+}
@@ -1 +1,202 @@
 package callback
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// group tracks the per-endpoint results of a single delivery to several endpoints
+// and reports an aggregate Summary once the outcome of the delivery is known.
+type group struct {
+	mu sync.Mutex
+
+	// Summary accumulated from the per-endpoint results.
+	summary Summary
+
+	// The number of endpoints that have not reported a result yet.
+	pending int
+
+	// The number of successful endpoints required for the delivery to succeed.
+	// Zero means the group waits for every endpoint, as in Broadcast mode.
+	required int
+
+	// Whether the aggregate result has already been reported.
+	done bool
+
+	// The response of the first endpoint that succeeded.
+	response *Response
+
+	// The attempts made on all endpoints that reported so far.
+	attempts []Attempt
+}
+
+// add records the result of one endpoint. It returns the aggregate Data and true
+// once the outcome is decided, otherwise false. Results arriving after the
+// outcome has been reported are ignored.
+func (g *group) add(data *Data) (Data, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// The aggregate result is reported only once.
+	if g.done {
+		return Data{}, false
+	}
+
+	// Count the result towards the summary.
+	g.attempts = append(g.attempts, data.Attempts...)
+	if data.Success {
+		g.summary.Succeeded++
+		if g.response == nil {
+			g.response = data.Response
+		}
+	} else {
+		g.summary.Failed++
+	}
+	g.pending--
+
+	return g.decide()
+}
+
+// fail counts n endpoints whose message could not be queued as failed. Like add,
+// it returns the aggregate Data and true if this decides the outcome.
+func (g *group) fail(n int) (Data, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.done {
+		return Data{}, false
+	}
+
+	g.summary.Failed += n
+	g.pending -= n
+
+	return g.decide()
+}
+
+// decide returns the aggregate Data and true once the outcome of the delivery is known,
+// and marks the group as done. It must be called with g.mu held.
+func (g *group) decide() (Data, bool) {
+	var success bool
+	switch {
+	case g.required > 0 && g.summary.Succeeded >= g.required:
+		// Enough endpoints acknowledged the message.
+		success = true
+	case g.required > 0 && g.summary.Succeeded+g.pending < g.required:
+		// The remaining endpoints can no longer reach the quorum.
+		success = false
+	case g.pending == 0:
+		// The broadcast is successful only if every attempted endpoint succeeded.
+		success = g.summary.Failed == 0 && g.summary.Succeeded > 0
+	default:
+		// Wait for more endpoints to report.
+		return Data{}, false
+	}
+	g.done = true
+
+	summary := g.summary
+	summary.Pending = g.pending
+	result := Data{
+		// Whether the delivery as a whole succeeded.
+		Success: success,
+		// The aggregate result of the delivery.
+		Summary: &summary,
+		// The attempts made on all endpoints.
+		Attempts: append([]Attempt(nil), g.attempts...),
+	}
+
+	// A successful delivery carries the first response, a failed one an error.
+	if success {
+		result.Response = g.response
+	} else {
+		result.Error = &Error{
+			Code:     0,
+			Message:  fmt.Sprintf("[ERROR] %d of %d endpoints failed", summary.Failed, summary.Total),
+			Critical: true,
+		}
+	}
+	return result, true
+}
+
+// broadcast sends the message to every available worker. Blocked workers are skipped
+// and counted in the aggregate Summary, which is passed to the On handler after
+// the per-endpoint results. If all workers are blocked, it returns an error.
+func (c *Callback) broadcast(msg *message) error {
+	return c.fanout(c.workers(), msg, 0)
+}
+
+// fanout enqueues a copy of the message on every available worker as one group.
+// The group succeeds once required workers acknowledge the message, or once every
+// worker has answered successfully if required is zero. If a worker does not accept the
+// message, because its context is done or the worker was closed, the error is returned
+// when no worker has accepted it yet. Otherwise the remaining workers are counted as failed
+// and the outcome is reported by the aggregate result like any other failure.
+func (c *Callback) fanout(workers []*Worker, msg *message, required int) error {
+	// Split workers into available and blocked ones before enqueuing anything,
+	// so the group knows how many results to wait for. Each available worker receives
+	// its own message sharing the same payload, with a probe slot reserved if it is half-open.
+	available := make([]*Worker, 0, len(workers))
+	clones := make([]*message, 0, len(workers))
+	for _, worker := range workers {
+		clone := *msg
+		if worker.reserve(&clone.probe) {
+			available = append(available, worker)
+			clones = append(clones, &clone)
+		}
+	}
+
+	// Nothing can be delivered if there are no available workers.
+	if len(available) == 0 {
+		return ErrAllBlocked
+	}
+
+	// The quorum cannot be reached if too many workers are blocked.
+	if len(available) < required {
+		releaseAll(available, clones)
+		return errors.New("not enough endpoints available to reach the quorum")
+	}
+
+	g := &group{
+		summary: Summary{
+			Total:   len(workers),
+			Skipped: len(workers) - len(available),
+			Quorum:  required,
+		},
+		pending:  len(available),
+		required: required,
+	}
+
+	// Every message belongs to the group.
+	for _, clone := range clones {
+		clone.group = g
+	}
+
+	for i, worker := range available {
+		if err := worker.push(clones[i]); err != nil {
+			releaseAll(available[i+1:], clones[i+1:])
+
+			// Nothing was sent, the caller learns about the failure.
+			if i == 0 {
+				return err
+			}
+
+			// The message is already on its way to some workers, so it is finished
+			// by the group, now if the failures decide the outcome or once they answer.
+			if summary, ok := g.fail(len(available) - i); ok {
+				summary.ID = msg.id
+				summary.Header = msg.header
+				c.finish(msg, summary)
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// releaseAll frees the probe slots reserved for messages that are not enqueued.
+func releaseAll(workers []*Worker, msgs []*message) {
+	for i, worker := range workers {
+		worker.release(msgs[i])
+	}
+}
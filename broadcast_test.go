@@ -1 +1,178 @@
 package callback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestGroup_Add tests that the aggregate result is reported only after the last endpoint.
+func TestGroup_Add(t *testing.T) {
+	g := &group{summary: Summary{Total: 3, Skipped: 1}, pending: 2}
+
+	// The first result must not complete the group.
+	if _, ok := g.add(&Data{Success: true}); ok {
+		t.Fatal("expected group to wait for the second endpoint")
+	}
+
+	// The second result completes the group with one failure.
+	data, ok := g.add(&Data{Success: false})
+	if !ok {
+		t.Fatal("expected group to be complete")
+	}
+	if data.Success {
+		t.Error("expected broadcast with a failed endpoint to be unsuccessful")
+	}
+	expected := Summary{Total: 3, Succeeded: 1, Failed: 1, Skipped: 1}
+	if *data.Summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, *data.Summary)
+	}
+}
+
+// TestGroup_Fail tests that endpoints which could not be sent the message
+// count as failed and decide the outcome like failed results.
+func TestGroup_Fail(t *testing.T) {
+	// The endpoint that was sent the message answers first.
+	g := &group{summary: Summary{Total: 2}, pending: 2}
+	if _, ok := g.add(&Data{Success: true}); ok {
+		t.Fatal("expected group to wait for the second endpoint")
+	}
+	data, ok := g.fail(1)
+	if !ok {
+		t.Fatal("expected group to be complete")
+	}
+	expected := Summary{Total: 2, Succeeded: 1, Failed: 1}
+	if data.Success || *data.Summary != expected {
+		t.Errorf("expected a failed delivery with summary %+v, got %+v", expected, data)
+	}
+
+	// Failures that make the quorum unreachable decide it before any endpoint answers.
+	g = &group{summary: Summary{Total: 3, Quorum: 2}, pending: 3, required: 2}
+	if data, ok := g.fail(2); !ok || data.Success {
+		t.Fatalf("expected the quorum to fail, got %+v", data)
+	}
+	if _, ok := g.add(&Data{Success: true}); ok {
+		t.Error("expected the aggregate result to be reported once")
+	}
+}
+
+// TestBroadcast_AllBlocked tests that an error is returned when all workers are blocked.
+func TestBroadcast_AllBlocked(t *testing.T) {
+	worker := &Worker{
+		messageQueue: make(chan *message, 1),
+		blockedUntil: time.Now().Add(time.Minute), // worker is blocked
+	}
+	callback := &Callback{
+		endPoints: []*Worker{worker},
+	}
+
+	err := callback.broadcast(newMessage(context.Background(), []byte("test data")))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// TestBroadcast_Delivery tests that every available endpoint receives the payload
+// and that the aggregate result counts successes, failures and skipped endpoints.
+func TestBroadcast_Delivery(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fail.Close()
+
+	clb := New(&Options{
+		DeliveryMode: Broadcast,
+		EndPoints:    []string{ok.URL, fail.URL, "http://blocked"},
+	})
+
+	// Block the last endpoint so it is skipped.
+	clb.endPoints[2].blockedUntil = time.Now().Add(time.Minute)
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte(`{"data": "test"}`)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Two per-endpoint results are followed by the aggregate result.
+	points := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case data := <-results:
+			if data.Summary == nil {
+				points[data.Point] = data.Success
+				continue
+			}
+			if i != 2 {
+				t.Fatal("expected the aggregate result after the per-endpoint results")
+			}
+			expected := Summary{Total: 3, Succeeded: 1, Failed: 1, Skipped: 1}
+			if *data.Summary != expected {
+				t.Errorf("expected summary %+v, got %+v", expected, *data.Summary)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for broadcast results")
+		}
+	}
+
+	if !points[ok.URL] || points[fail.URL] {
+		t.Errorf("unexpected per-endpoint results %v", points)
+	}
+}
+
+// TestBroadcast_PartialEnqueue tests that the aggregate result is reported
+// when the message context is done before every endpoint accepted the message.
+func TestBroadcast_PartialEnqueue(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	tr := &orderTransport{release: release, failed: make(map[string]bool)}
+	clb := New(&Options{Transport: tr, DeliveryMode: Broadcast, EndPoints: []string{"a", "b"}})
+	defer clb.Close()
+
+	results := make(chan *Data, 200)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	// Hold the second endpoint and fill its queue.
+	busy := clb.endPoints[1]
+	busy.enqueue(newMessage(context.Background(), []byte("hold")))
+	for len(busy.messageQueue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < cap(busy.messageQueue); i++ {
+		busy.enqueue(newMessage(context.Background(), []byte("queued")))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, err := clb.EmitContext(ctx, []byte("payload")); err != nil {
+		t.Fatalf("expected the message accepted by an endpoint to be emitted, got %v", err)
+	}
+
+	for {
+		select {
+		case data := <-results:
+			if data.Summary == nil {
+				continue
+			}
+			expected := Summary{Total: 2, Succeeded: 1, Failed: 1}
+			if data.Success || *data.Summary != expected {
+				t.Errorf("expected a failed delivery with summary %+v, got %+v", expected, data)
+			}
+			return
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for the aggregate result")
+		}
+	}
+}
@@ -1,46 +1,128 @@
 package callback
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/gmelum/callback/transport"
 )
 
 // Callback manages the sending of messages to multiple worker endpoints with configurable retry settings and delivery modes.
 type Callback struct {
-	transport       Transport     // Transport defines the method of communication with workers.
-	deliveryMode    DeliveryMode  // DeliveryMode controls how messages are sent: RoundRobin or Broadcast.
-	endPoints       []*Worker     // List of worker endpoints that handle message delivery.
-	retryLimit      int           // Number of retry attempts allowed before giving up.
-	retryTimeout    time.Duration // Wait time between retry attempts.
-	retryWindow     time.Duration // Time window in which retries are allowed.
-	roundRobinIndex atomic.Int32  // Index used for RoundRobin delivery mode to track the last worker.
-	returnChannel   chan Data     // Channel for returning data back to the callback function.
-	mu              sync.Mutex    // Mutex for concurrent access to endpoints.
+	transport        Transport                // Transport defines the method of communication with workers.
+	deliveryMode     DeliveryMode             // DeliveryMode controls how messages are sent to the workers.
+	retryMode        RetryMode                // RetryMode controls where failed messages are retried: Repeat or Next.
+	endPoints        []*Worker                // List of worker endpoints that handle message delivery.
+	retryLimit       int                      // Number of retry attempts allowed before giving up.
+	retryTimeout     time.Duration            // Wait time between retry attempts.
+	retryWindow      time.Duration            // Time window in which retries are allowed.
+	backoff          *Backoff                 // Backoff policy for retries and block periods, nil for none.
+	outbox           *Outbox                  // Persistent log of emitted messages, nil if disabled.
+	deadLetters      DeadLetter               // Sink for messages that exhausted their retries, nil if disabled.
+	halfOpenProbes   int                      // Number of probe messages let through to a half-open endpoint at a time.
+	successThreshold int                      // Number of consecutive successful probes that close a half-open endpoint.
+	quorumCount      int                      // Number of acknowledgements required in Quorum mode.
+	quorumRatio      float64                  // Fraction of endpoints required in Quorum mode.
+	roundRobinIndex  atomic.Uint32            // Index used for RoundRobin delivery mode to track the last worker.
+	ring             atomic.Pointer[hashRing] // Hash ring used for ConsistentHash delivery mode.
+	virtualNodes     int                      // Number of virtual nodes per endpoint on the hash ring.
+	sequencer        *sequencer               // Sequencer of messages sharing a key in Ordered mode, nil if disabled.
+	hedge            *Hedge                   // Hedged requests configuration, nil if disabled.
+	latency          *latencies               // Latencies of recent requests for the hedge percentile, nil if unused.
+	cloudEventMode   CloudEventMode           // Mode in which EmitEvent encodes CloudEvents.
+	healthCheck      *HealthCheck             // Active health check configuration, nil if disabled.
+	healthREST       *transport.REST          // REST transport whose clients send the active health checks.
+	returnChannel    chan Data                // Channel for returning data back to the callback function.
+	eventChannel     chan Event               // Channel for reporting endpoint state transitions.
+	weights          map[string]int           // Weights of endpoints in WeightedRoundRobin mode, a copy of Options.Weights guarded by mu.
+	priorities       map[string]int           // Priority tiers of endpoints in Failover mode.
+	mu               sync.Mutex               // Mutex for concurrent access to endpoints.
+	balanceMu        sync.Mutex               // Mutex for concurrent access to the load balancing state of workers.
 
-	callback func(data *Data) // User-defined callback function to handle processed data.
+	callback func(data *Data)   // User-defined callback function to handle processed data.
+	onEvent  func(event *Event) // User-defined callback function to handle endpoint events.
 }
 
 // New initializes a new Callback instance with the provided options and sets up worker endpoints.
 func New(opt *Options) *Callback {
 	opt = defaultOptions(opt) // Apply default options if not provided.
 
+	// Give the callback its own instance of a built-in transport, configured with the options,
+	// so that closing the callback does not close the connections of other callbacks.
+	tr := opt.Transport
+	switch tr {
+	case REST:
+		tr = &transport.REST{
+			Signing:     opt.Signing,
+			TLS:         opt.TLS,
+			EndPointTLS: opt.EndPointTLS,
+			HTTP:        opt.HTTP,
+		}
+	case QUIC:
+		tr = transport.NewQUIC(nil)
+	}
+
+	// Health checks are HTTP requests, so they are only sent through the REST transport.
+	rest, _ := tr.(*transport.REST)
+
 	// Create a Callback instance and initialize fields with options.
 	callback := &Callback{
-		transport:     opt.Transport,
-		deliveryMode:  opt.DeliveryMode,
-		retryLimit:    opt.RetryLimit,
-		retryTimeout:  opt.RetryTimeout,
-		retryWindow:   opt.RetryWindow,
-		returnChannel: make(chan Data, 100),
+		transport:        tr,
+		deliveryMode:     opt.DeliveryMode,
+		retryMode:        opt.RetryMode,
+		retryLimit:       opt.RetryLimit,
+		retryTimeout:     opt.RetryTimeout,
+		retryWindow:      opt.RetryWindow,
+		backoff:          opt.Backoff,
+		outbox:           opt.Outbox,
+		deadLetters:      opt.DeadLetter,
+		quorumCount:      opt.Quorum,
+		quorumRatio:      opt.QuorumRatio,
+		halfOpenProbes:   opt.HalfOpenProbes,
+		successThreshold: opt.SuccessThreshold,
+		weights:          maps.Clone(opt.Weights),
+		virtualNodes:     opt.VirtualNodes,
+		priorities:       opt.Priorities,
+		hedge:            opt.Hedge,
+		cloudEventMode:   opt.CloudEventMode,
+		returnChannel:    make(chan Data, 100),
+		eventChannel:     make(chan Event, 100),
+	}
+	// Check the health of endpoints if requested and the endpoints are HTTP URLs.
+	if opt.HealthCheck != nil && rest != nil {
+		callback.healthCheck = opt.HealthCheck
+		callback.healthREST = rest
+	}
+
+	// Deliver messages sharing a key in emission order if requested.
+	if opt.Ordered {
+		callback.sequencer = newSequencer()
+	}
+
+	// Track request latencies if requests are hedged at a percentile.
+	if opt.Hedge != nil && opt.Hedge.Percentile > 0 {
+		callback.latency = newLatencies(opt.Hedge.Samples)
 	}
+
 	// Sync the initial set of endpoints provided in options.
 	callback.SyncEndPoint(opt.EndPoints)
 
 	// Launch a handler goroutine to listen on the return channel for incoming data.
 	go callback.handler()
 
+	// Launch a handler goroutine to listen on the event channel.
+	go callback.eventHandler()
+
+	// Replay the messages left undelivered by a previous process.
+	if callback.outbox != nil {
+		go callback.replay()
+	}
+
 	return callback
 }
 
@@ -77,6 +159,29 @@ func (c *Callback) handler() {
 	}
 }
 
+// eventHandler listens on the eventChannel and invokes the OnEvent function when an event is received.
+func (c *Callback) eventHandler() {
+	// Recover from panics in the user-defined function to keep the handler operational.
+	defer func() {
+		if r := recover(); r != nil {
+			go c.eventHandler()
+		}
+	}()
+
+	for event := range c.eventChannel {
+		if c.onEvent != nil {
+			c.onEvent(&event)
+		}
+	}
+}
+
+// event reports an endpoint event. Callbacks created without New do not report events.
+func (c *Callback) event(event Event) {
+	if c.eventChannel != nil {
+		c.eventChannel <- event
+	}
+}
+
 // findWorkerIndex locates the index of a worker based on its endpoint.
 func (c *Callback) findWorkerIndex(endPoint string) int {
 	for i, worker := range c.endPoints {
@@ -87,42 +192,67 @@ func (c *Callback) findWorkerIndex(endPoint string) int {
 	return -1 // Return -1 if worker is not found.
 }
 
-// AddEndpoint adds a new worker for the given endpoint.
-func (c *Callback) AddEndpoint(host string) {
+// workers returns a snapshot of the current worker endpoints.
+func (c *Callback) workers() []*Worker {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// If the worker already exists, do not add again.
-	if c.findWorkerIndex(host) != -1 {
+	return append([]*Worker(nil), c.endPoints...)
+}
+
+// AddEndpoint adds a new worker for the given endpoint. An optional weight is used
+// in WeightedRoundRobin mode; giving it for an existing endpoint changes its weight.
+func (c *Callback) AddEndpoint(host string, weight ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Remember the weight for the endpoint.
+	if len(weight) > 0 {
+		if c.weights == nil {
+			c.weights = make(map[string]int)
+		}
+		c.weights[host] = weight[0]
+	}
+
+	// If the worker already exists, do not add again, only update its weight.
+	if index := c.findWorkerIndex(host); index != -1 {
+		if len(weight) > 0 {
+			c.setWeight(c.endPoints[index], weight[0])
+		}
 		return
 	}
 
 	// Create and add a new worker for the endpoint.
 	worker := NewWorker(c, host)
 	c.endPoints = append(c.endPoints, worker)
+	c.rebuildRing()
 }
 
 // DeleteEndpoint removes and closes the worker for the given endpoint.
 func (c *Callback) DeleteEndpoint(host string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	// Find the index of the worker to delete.
 	index := c.findWorkerIndex(host)
 	if index == -1 {
+		c.mu.Unlock()
 		return // Worker not found, exit without action.
 	}
 
-	// Close the worker and remove it from the slice.
-	c.endPoints[index].Close()
+	// Remove the worker from the slice.
+	worker := c.endPoints[index]
 	c.endPoints = append(c.endPoints[:index], c.endPoints[index+1:]...)
+	c.rebuildRing()
+	c.mu.Unlock()
+
+	// Close the worker once the lock is released, see closeWorkers.
+	closeWorkers([]*Worker{worker})
 }
 
 // SyncEndPoint synchronizes the current list of endpoints with a new list.
-// It removes outdated workers and adds new ones.
+// It removes outdated workers and adds new ones. Existing workers keep their weight.
 func (c *Callback) SyncEndPoint(hosts []string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	// Map for tracking new hosts and their presence.
 	newHosts := make(map[string]struct{}, len(hosts))
@@ -131,10 +261,11 @@ func (c *Callback) SyncEndPoint(hosts []string) {
 	}
 
 	// Remove outdated workers that are not in the new list of hosts.
+	var removed []*Worker
 	for i := len(c.endPoints) - 1; i >= 0; i-- {
 		worker := c.endPoints[i]
 		if _, exists := newHosts[worker.point]; !exists {
-			worker.Close()
+			removed = append(removed, worker)
 			c.endPoints = append(c.endPoints[:i], c.endPoints[i+1:]...) // Remove outdated worker.
 		}
 	}
@@ -145,22 +276,235 @@ func (c *Callback) SyncEndPoint(hosts []string) {
 			c.endPoints = append(c.endPoints, NewWorker(c, host))
 		}
 	}
+	c.rebuildRing()
+	c.mu.Unlock()
+
+	// Close the removed workers once the lock is released, see closeWorkers.
+	closeWorkers(removed)
+}
+
+// closeWorkers closes workers that were removed from the endpoints. Closing a worker
+// reports its queued messages to On, which may call back into the Callback,
+// so it must not be called with c.mu held.
+func closeWorkers(workers []*Worker) {
+	for _, worker := range workers {
+		worker.Close()
+	}
+}
+
+// Emit sends data to the workers based on the delivery mode and returns the generated message ID.
+func (c *Callback) Emit(data []byte) (string, error) {
+	return c.EmitContext(context.Background(), data)
 }
 
-// Emit sends data to the workers based on the delivery mode.
-func (c *Callback) Emit(data []byte) error {
+// EmitContext sends data to the workers based on the delivery mode and returns the generated
+// message ID. It stops waiting for room in a worker's queue when ctx is done, and ctx also
+// bounds the transport request, so the message fails once the ctx deadline passes or ctx is cancelled.
+func (c *Callback) EmitContext(ctx context.Context, data []byte) (string, error) {
+	return c.EmitMessage(ctx, &Message{Data: data})
+}
+
+// EmitMessage sends the message like EmitContext and returns its ID,
+// which is generated if the message has none.
+// If an Outbox is configured, it returns only after the message is durably recorded.
+func (c *Callback) EmitMessage(ctx context.Context, m *Message) (string, error) {
+	msg := newMessage(ctx, m.Data)
+	msg.header = m.Header
+	msg.key = m.Key
+	if m.ID != "" {
+		msg.id = m.ID
+	}
+
+	if err := c.dispatch(msg); err != nil {
+		return "", err
+	}
+	return msg.id, nil
+}
+
+// dispatch records the message in the outbox, if any, and emits it.
+func (c *Callback) dispatch(msg *message) error {
+	// Record the message before dispatching it.
+	if c.outbox != nil {
+		if err := c.outbox.put(msg); err != nil {
+			return err
+		}
+	}
+
+	if err := c.send(msg); err != nil {
+		// The caller learns about the failure, so the message must not be replayed.
+		if c.outbox != nil {
+			if ackErr := c.outbox.ack(msg.id); ackErr != nil {
+				return errors.Join(err, ackErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// replay dispatches the undelivered messages recorded in the outbox by a previous process.
+// Messages that cannot be dispatched stay in the outbox for the next start.
+func (c *Callback) replay() {
+	for _, record := range c.outbox.replay() {
+		msg := newMessage(context.Background(), record.Data)
+		msg.id = record.ID
+		msg.header = record.Header
+		msg.key = record.Key
+		c.send(msg)
+	}
+}
+
+// finish completes a message once its final result is known and, in Ordered mode,
+// lets the next message with the same key go.
+func (c *Callback) finish(msg *message, data Data) {
+	c.complete(msg, data)
+
+	// The next message is emitted in the background, as it may have to wait for room
+	// in the queue of the worker calling finish.
+	if c.sequencer != nil && msg.key != "" {
+		go c.advance(msg.key)
+	}
+}
+
+// complete records the final result of a message, then reports it to the On handler and
+// answers a pending Request, so that a reported failure is already dead-lettered.
+func (c *Callback) complete(msg *message, data Data) {
+	c.settle(msg, data)
+
+	c.returnChannel <- data
+	msg.answer(data)
+}
+
+// settle dead-letters a failed message and marks the message in the outbox once its result
+// is final. Messages failed by their own context are not dead-lettered, and are final as the
+// caller gave up on them. Other failures are final once dead-lettered, or right away without a
+// DeadLetter, as they are reported to On; a failure to dead-letter keeps the message for replay.
+func (c *Callback) settle(msg *message, data Data) {
+	done := true
+	if !data.Success && msg.ctx.Err() == nil && c.deadLetters != nil {
+		done = c.deadLetter(msg, data)
+	}
+
+	if c.outbox != nil && done {
+		c.outbox.ack(msg.id)
+	}
+}
+
+// Request sends data like EmitContext and waits for the result of this message.
+// It returns the endpoint's Response, or an *Error if the delivery failed. In Broadcast
+// and Quorum modes, the result is the aggregate one and the Response is that of the first
+// endpoint that succeeded. The result is passed to the On handler as well.
+func (c *Callback) Request(ctx context.Context, data []byte) (*Response, error) {
+	msg := newMessage(ctx, data)
+	msg.reply = make(chan Data, 1)
+
+	if err := c.dispatch(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-msg.reply:
+		if !result.Success {
+			return nil, result.Error
+		}
+		return result.Response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// next returns the next available worker for a single delivery of the message
+// according to the delivery mode, or nil if all workers are blocked.
+func (c *Callback) next(msg *message) *Worker {
+	switch c.deliveryMode {
+	case WeightedRoundRobin:
+		return c.nextWeighted()
+	case LeastOutstanding:
+		return c.nextLeastOutstanding()
+	case ConsistentHash:
+		return c.nextHash(msg)
+	case Failover:
+		return c.nextFailover()
+	default:
+		return c.nextRoundRobin()
+	}
+}
+
+// enqueueNext enqueues the message on the next available worker returned by pick.
+// A worker that stops accepting messages between being picked and the enqueue, such as
+// a half-open worker whose probe slots were taken meanwhile, is passed over.
+// If all workers are blocked, it returns ErrAllBlocked.
+func (c *Callback) enqueueNext(msg *message, pick func() *Worker) error {
+	for range len(c.workers()) + 1 {
+		worker := pick()
+		if worker == nil {
+			break
+		}
+		if err := worker.enqueue(msg); !errors.Is(err, errUnavailable) {
+			return err
+		}
+	}
+	return ErrAllBlocked
+}
+
+// reserveNext returns the next available worker chosen by the delivery mode with a probe
+// slot reserved for the message if the worker is half-open, or nil if there is none.
+func (c *Callback) reserveNext(msg *message) *Worker {
+	for range len(c.workers()) + 1 {
+		worker := c.next(msg)
+		if worker == nil {
+			return nil
+		}
+		if worker.reserve(&msg.probe) {
+			return worker
+		}
+	}
+	return nil
+}
+
+// emit dispatches the message to the workers based on the delivery mode.
+func (c *Callback) emit(msg *message) error {
 	switch c.deliveryMode {
 	case RoundRobin:
-		c.roundRobin(data)
-		return nil
+		return c.roundRobin(msg)
+	case WeightedRoundRobin:
+		return c.weightedRoundRobin(msg)
+	case LeastOutstanding:
+		return c.leastOutstanding(msg)
+	case ConsistentHash:
+		return c.consistentHash(msg)
+	case Failover:
+		return c.failover(msg)
 	case Broadcast:
-		// Broadcast to all workers (functionality to be implemented).
-		return nil
+		return c.broadcast(msg)
+	case Quorum:
+		return c.quorum(msg)
 	}
 	return nil
 }
 
+// Close stops all workers and closes the transport. A transport passed in Options
+// is closed as well, so it must not be shared with other callbacks.
+func (c *Callback) Close() error {
+	c.mu.Lock()
+
+	// Forget the endpoints, then stop every worker once the lock is released.
+	workers := c.endPoints
+	c.endPoints = nil
+	c.rebuildRing()
+	c.mu.Unlock()
+
+	closeWorkers(workers)
+
+	return c.transport.Close()
+}
+
 // On sets a callback function to handle processed data received from the returnChannel.
 func (c *Callback) On(clb func(data *Data)) {
 	c.callback = clb
 }
+
+// OnEvent sets a callback function to handle endpoint events, such as circuit breaker state transitions.
+func (c *Callback) OnEvent(clb func(event *Event)) {
+	c.onEvent = clb
+}
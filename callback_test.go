@@ -1 +1,410 @@
 package callback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// testTransport is a Transport that records sent payloads and answers with a fixed result.
+type testTransport struct {
+	mu     sync.Mutex
+	sent   []string
+	ids    []string
+	header map[string]string
+	err    error
+	closed bool
+}
+
+func (t *testTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sent = append(t.sent, endpoint+" "+string(msg.Data))
+	t.ids = append(t.ids, msg.ID)
+	t.header = msg.Header
+	if t.err != nil {
+		return nil, t.err
+	}
+	return []byte("ok"), nil
+}
+
+func (t *testTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	return nil
+}
+
+// waitData waits for the next Data passed to the On handler.
+func waitData(t *testing.T, results chan *Data) *Data {
+	t.Helper()
+
+	select {
+	case data := <-results:
+		return data
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for result")
+		return nil
+	}
+}
+
+// TestCallback_CustomTransport tests that a custom Transport plugged in through Options is used by workers.
+func TestCallback_CustomTransport(t *testing.T) {
+	tr := &testTransport{}
+	clb := New(&Options{
+		Transport: tr,
+		EndPoints: []string{"custom://a"},
+	})
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if !data.Success || string(data.Response.Data) != "ok" {
+		t.Errorf("expected successful response, got %+v", data)
+	}
+
+	tr.mu.Lock()
+	if len(tr.sent) != 1 || tr.sent[0] != "custom://a payload" {
+		t.Errorf("unexpected sent payloads %v", tr.sent)
+	}
+	tr.mu.Unlock()
+
+	// Closing the callback must close the transport.
+	if err := clb.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !tr.closed {
+		t.Error("expected transport to be closed")
+	}
+}
+
+// TestCallback_TransportError tests that a transport error is reported to the On handler.
+func TestCallback_TransportError(t *testing.T) {
+	clb := New(&Options{
+		Transport: &testTransport{err: errors.New("refused")},
+		EndPoints: []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if data.Success || data.Error == nil {
+		t.Errorf("expected error result, got %+v", data)
+	}
+}
+
+// deadlineTransport is a Transport that reports whether requests carry a deadline.
+type deadlineTransport struct{}
+
+func (t *deadlineTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("no deadline")
+	}
+	return []byte("deadline"), nil
+}
+
+func (t *deadlineTransport) Close() error {
+	return nil
+}
+
+// TestCallback_EmitContext tests that the context deadline is propagated to the transport.
+func TestCallback_EmitContext(t *testing.T) {
+	clb := New(&Options{
+		Transport: &deadlineTransport{},
+		EndPoints: []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if _, err := clb.EmitContext(ctx, []byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if !data.Success {
+		t.Errorf("expected the transport to receive the deadline, got %+v", data.Error)
+	}
+}
+
+// TestCallback_EmitContextFullQueue tests that EmitContext stops waiting for a full queue when the context is done.
+func TestCallback_EmitContextFullQueue(t *testing.T) {
+	clb := &Callback{
+		deliveryMode: RoundRobin,
+		endPoints: []*Worker{
+			{messageQueue: make(chan *message)}, // nobody reads the queue
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	_, err := clb.EmitContext(ctx, []byte("payload"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// TestCallback_EmitContextCancelled tests that a message cancelled while queued is reported without being sent.
+func TestCallback_EmitContextCancelled(t *testing.T) {
+	tr := &testTransport{}
+	clb := New(&Options{Transport: tr})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	// Add a worker whose handler is started only after the cancelled message is queued.
+	worker := &Worker{
+		callback:      clb,
+		point:         "custom://a",
+		messageQueue:  make(chan *message, 1),
+		returnChannel: clb.returnChannel,
+		stop:          make(chan struct{}),
+	}
+	clb.endPoints = append(clb.endPoints, worker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := clb.EmitContext(ctx, []byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	cancel()
+	go worker.handler()
+
+	data := waitData(t, results)
+	if data.Success || len(data.Attempts) != 0 {
+		t.Errorf("expected failure without attempts, got %+v", data)
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.sent) != 0 {
+		t.Errorf("expected nothing to be sent, got %v", tr.sent)
+	}
+}
+
+// TestCallback_Request tests that Request returns the result of its own message.
+func TestCallback_Request(t *testing.T) {
+	t.Run("Response", func(t *testing.T) {
+		clb := New(&Options{
+			Transport: &testTransport{},
+			EndPoints: []string{"custom://a"},
+		})
+		defer clb.Close()
+
+		res, err := clb.Request(context.Background(), []byte("payload"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(res.Data) != "ok" {
+			t.Errorf("expected response ok, got %s", res.Data)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		clb := New(&Options{
+			Transport:  &testTransport{err: errors.New("refused")},
+			RetryLimit: 1,
+			EndPoints:  []string{"custom://a"},
+		})
+		defer clb.Close()
+
+		_, err := clb.Request(context.Background(), []byte("payload"))
+		var failure *Error
+		if !errors.As(err, &failure) {
+			t.Fatalf("expected *Error, got %v", err)
+		}
+	})
+
+	t.Run("Broadcast", func(t *testing.T) {
+		clb := New(&Options{
+			Transport:    &testTransport{},
+			DeliveryMode: Broadcast,
+			EndPoints:    []string{"custom://a", "custom://b"},
+		})
+		defer clb.Close()
+
+		res, err := clb.Request(context.Background(), []byte("payload"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(res.Data) != "ok" {
+			t.Errorf("expected response ok, got %s", res.Data)
+		}
+	})
+
+	t.Run("ContextDone", func(t *testing.T) {
+		clb := &Callback{
+			deliveryMode: RoundRobin,
+			endPoints: []*Worker{
+				{messageQueue: make(chan *message, 1)}, // nobody processes the queue
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+
+		if _, err := clb.Request(ctx, []byte("payload")); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+	})
+}
+
+// TestNew_Signing tests that signing options configure a dedicated REST transport.
+func TestNew_Signing(t *testing.T) {
+	signing := &Signing{Secrets: []string{"secret"}}
+	clb := New(&Options{Signing: signing})
+	defer clb.Close()
+
+	rest, ok := clb.transport.(*transport.REST)
+	if !ok || rest == REST || rest.Signing != signing {
+		t.Errorf("expected a dedicated REST transport with the signing options, got %v", clb.transport)
+	}
+
+	// The shared REST transport stays unsigned.
+	if REST.(*transport.REST).Signing != nil {
+		t.Error("expected the shared REST transport to stay unsigned")
+	}
+}
+
+// TestNew_TLS tests that TLS options configure a dedicated REST transport.
+func TestNew_TLS(t *testing.T) {
+	config := &TLS{CAFile: "ca.pem"}
+	endpoint := map[string]*TLS{"https://a": {ServerName: "a"}}
+	clb := New(&Options{TLS: config, EndPointTLS: endpoint})
+	defer clb.Close()
+
+	rest, ok := clb.transport.(*transport.REST)
+	if !ok || rest == REST || rest.TLS != config || rest.EndPointTLS["https://a"] != endpoint["https://a"] {
+		t.Errorf("expected a dedicated REST transport with the TLS options, got %v", clb.transport)
+	}
+}
+
+// TestNew_HTTP tests that HTTP options configure a dedicated REST transport.
+func TestNew_HTTP(t *testing.T) {
+	options := &HTTP{Timeout: time.Second}
+	clb := New(&Options{HTTP: options})
+	defer clb.Close()
+
+	rest, ok := clb.transport.(*transport.REST)
+	if !ok || rest == REST || rest.HTTP != options {
+		t.Errorf("expected a dedicated REST transport with the HTTP options, got %v", clb.transport)
+	}
+}
+
+// TestSyncEndPoint_Reentrant tests that removing endpoints with many queued messages
+// does not deadlock when the On handler calls back into the Callback.
+func TestSyncEndPoint_Reentrant(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	tr := &orderTransport{release: release, failed: make(map[string]bool)}
+	clb := New(&Options{Transport: tr, EndPoints: []string{"a", "b"}})
+	defer clb.Close()
+
+	clb.On(func(data *Data) {
+		clb.Health()
+	})
+
+	// Hold both endpoints, then fill their queues beyond the capacity of the return channel.
+	clb.Emit([]byte("hold"))
+	clb.Emit([]byte("hold"))
+	for _, worker := range clb.workers() {
+		for len(worker.messageQueue) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i := 0; i < 180; i++ {
+		if _, err := clb.Emit([]byte("queued")); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		clb.SyncEndPoint([]string{"other"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected SyncEndPoint to return")
+	}
+}
+
+// TestRequest_RemovedEndpoint tests that a Request queued on a removed endpoint is answered.
+func TestRequest_RemovedEndpoint(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	tr := &orderTransport{release: release, failed: make(map[string]bool)}
+	clb := New(&Options{Transport: tr, EndPoints: []string{"a"}})
+	defer clb.Close()
+
+	clb.Emit([]byte("hold"))
+	for len(clb.workers()[0].messageQueue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := clb.Request(context.Background(), []byte("queued"))
+		errs <- err
+	}()
+
+	// Wait until the request is queued behind the held message.
+	for len(clb.workers()[0].messageQueue) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clb.DeleteEndpoint("a")
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected the request to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the request to be answered")
+	}
+}
+
+// TestNew_OwnTransport tests that every callback gets its own built-in transport,
+// so closing one callback does not close the connections of another.
+func TestNew_OwnTransport(t *testing.T) {
+	for _, shared := range []Transport{REST, QUIC} {
+		first := New(&Options{Transport: shared})
+		second := New(&Options{Transport: shared})
+		defer second.Close()
+
+		if first.transport == shared || second.transport == shared || first.transport == second.transport {
+			t.Errorf("expected every callback to get its own transport, got %p and %p", first.transport, second.transport)
+		}
+		first.Close()
+	}
+}
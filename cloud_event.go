@@ -0,0 +1,266 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// CloudEventsVersion is the version of the CloudEvents specification events conform to.
+const CloudEventsVersion = "1.0"
+
+// CloudEvents content types.
+const (
+	// ContentTypeCloudEvent is the content type of an event sent in structured mode.
+	ContentTypeCloudEvent = "application/cloudevents+json; charset=UTF-8"
+
+	// ContentTypeCloudEventBatch is the content type of a batch of events.
+	ContentTypeCloudEventBatch = "application/cloudevents-batch+json; charset=UTF-8"
+)
+
+// CloudEventMode defines how a CloudEvent is encoded in a message.
+type CloudEventMode string
+
+var (
+	// BinaryMode sends the attributes of an event as "ce-" headers and its data as the body,
+	// with the data content type as the Content-Type of the message.
+	BinaryMode CloudEventMode = "binary"
+
+	// StructuredMode sends the whole event, attributes and data, as a JSON object
+	// with the application/cloudevents+json content type.
+	StructuredMode CloudEventMode = "structured"
+)
+
+// CloudEvent is an event as defined by the CloudEvents 1.0 specification.
+// Create events with NewCloudEvent and emit them with EmitEvent.
+type CloudEvent struct {
+
+	// ID identifies the event. Together with Source, it must be unique.
+	ID string
+
+	// Source identifies the context in which the event happened, such as a URI.
+	Source string
+
+	// Type is the type of the event, such as "com.example.order.created".
+	Type string
+
+	// Time is when the event happened. It is not sent if zero.
+	Time time.Time
+
+	// Subject is the subject of the event within the context of the source, optional.
+	Subject string
+
+	// DataContentType is the content type of Data.
+	// Default value: application/json
+	DataContentType string
+
+	// DataSchema is the URI of the schema Data adheres to, optional.
+	DataSchema string
+
+	// Extensions holds extension attributes by name. Names consist of lowercase letters
+	// and digits. The "partitionkey" extension is used as the Message.Key of the event.
+	Extensions map[string]string
+
+	// Data is the payload of the event.
+	Data []byte
+}
+
+// NewCloudEvent creates an event of the given source and type carrying JSON data,
+// with a new ID and the current time.
+func NewCloudEvent(source, eventType string, data []byte) *CloudEvent {
+	return &CloudEvent{
+		ID:              newID(),
+		Source:          source,
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// validate checks that the event has its required attributes and valid extension names.
+func (e *CloudEvent) validate() error {
+	if e.ID == "" || e.Source == "" || e.Type == "" {
+		return errors.New("cloud event requires id, source and type")
+	}
+
+	for name := range e.Extensions {
+		if !validExtension(name) {
+			return fmt.Errorf("invalid cloud event extension name %q", name)
+		}
+	}
+	return nil
+}
+
+// attributes returns the context attributes of the event by name, omitting empty ones.
+func (e *CloudEvent) attributes() map[string]string {
+	attributes := make(map[string]string, 8+len(e.Extensions))
+	for name, value := range e.Extensions {
+		attributes[name] = value
+	}
+
+	attributes["specversion"] = CloudEventsVersion
+	attributes["id"] = e.ID
+	attributes["source"] = e.Source
+	attributes["type"] = e.Type
+	if !e.Time.IsZero() {
+		attributes["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+	if e.Subject != "" {
+		attributes["subject"] = e.Subject
+	}
+	if e.DataContentType != "" {
+		attributes["datacontenttype"] = e.DataContentType
+	}
+	if e.DataSchema != "" {
+		attributes["dataschema"] = e.DataSchema
+	}
+	return attributes
+}
+
+// structured returns the JSON representation of the event used in structured and batch mode.
+// JSON data is embedded as is, other data is base64-encoded in data_base64.
+func (e *CloudEvent) structured() (map[string]any, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	event := make(map[string]any)
+	for name, value := range e.attributes() {
+		event[name] = value
+	}
+
+	if len(e.Data) > 0 {
+		if isJSON(e.DataContentType) && json.Valid(e.Data) {
+			event["data"] = json.RawMessage(e.Data)
+		} else {
+			event["data_base64"] = e.Data // Marshalled as base64.
+		}
+	}
+	return event, nil
+}
+
+// Message encodes the event as a message in the given mode. The message has the ID
+// of the event, and the "partitionkey" extension, if any, as its key.
+func (e *CloudEvent) Message(mode CloudEventMode) (*Message, error) {
+	msg := &Message{ID: e.ID, Key: e.Extensions["partitionkey"]}
+
+	if mode == StructuredMode {
+		event, err := e.structured()
+		if err != nil {
+			return nil, err
+		}
+		if msg.Data, err = json.Marshal(event); err != nil {
+			return nil, err
+		}
+		msg.Header = map[string]string{"Content-Type": ContentTypeCloudEvent}
+		return msg, nil
+	}
+
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	// In binary mode, the data content type is the content type of the message.
+	msg.Header = make(map[string]string)
+	for name, value := range e.attributes() {
+		if name == "datacontenttype" {
+			msg.Header["Content-Type"] = value
+			continue
+		}
+		msg.Header["ce-"+name] = encodeHeader(value)
+	}
+	msg.Data = e.Data
+	return msg, nil
+}
+
+// CloudEventBatch encodes events as a single message in batch mode, a JSON array of
+// structured events with the application/cloudevents-batch+json content type.
+// The message gets a new ID.
+func CloudEventBatch(events []*CloudEvent) (*Message, error) {
+	batch := make([]map[string]any, 0, len(events))
+	for _, e := range events {
+		event, err := e.structured()
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, event)
+	}
+
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		ID:     newID(),
+		Header: map[string]string{"Content-Type": ContentTypeCloudEventBatch},
+		Data:   data,
+	}, nil
+}
+
+// EmitEvent sends a CloudEvent like EmitMessage, encoded in the mode set by
+// Options.CloudEventMode, and returns the ID of the event.
+func (c *Callback) EmitEvent(ctx context.Context, event *CloudEvent) (string, error) {
+	msg, err := event.Message(c.cloudEventMode)
+	if err != nil {
+		return "", err
+	}
+	return c.EmitMessage(ctx, msg)
+}
+
+// EmitEvents sends CloudEvents as a single message in batch mode and returns the message ID.
+func (c *Callback) EmitEvents(ctx context.Context, events []*CloudEvent) (string, error) {
+	msg, err := CloudEventBatch(events)
+	if err != nil {
+		return "", err
+	}
+	return c.EmitMessage(ctx, msg)
+}
+
+// isJSON reports whether a data content type denotes JSON. Events without a content type hold JSON.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validExtension reports whether name is a valid extension attribute name, made of
+// lowercase letters and digits, that does not clash with a context attribute.
+func validExtension(name string) bool {
+	switch name {
+	case "", "specversion", "id", "source", "type", "time", "subject", "datacontenttype", "dataschema", "data", "data_base64":
+		return false
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeHeader percent-encodes a header value as required by the CloudEvents HTTP binding:
+// spaces, double quotes, percent signs and bytes outside printable ASCII are encoded.
+func encodeHeader(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
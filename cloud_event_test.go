@@ -0,0 +1,174 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCloudEvent_Message tests encoding events in binary and structured mode.
+func TestCloudEvent_Message(t *testing.T) {
+	event := NewCloudEvent("/orders", "com.example.order.created", []byte(`{"order":1}`))
+	event.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	event.Subject = "order 1"
+	event.Extensions = map[string]string{"partitionkey": "customer-1"}
+
+	t.Run("binary", func(t *testing.T) {
+		msg, err := event.Message(BinaryMode)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected := map[string]string{
+			"ce-specversion":  "1.0",
+			"ce-id":           event.ID,
+			"ce-source":       "/orders",
+			"ce-type":         "com.example.order.created",
+			"ce-time":         "2024-01-02T03:04:05Z",
+			"ce-subject":      "order%201",
+			"ce-partitionkey": "customer-1",
+			"Content-Type":    "application/json",
+		}
+		for name, value := range expected {
+			if msg.Header[name] != value {
+				t.Errorf("expected header %s to be %q, got %q", name, value, msg.Header[name])
+			}
+		}
+		if len(msg.Header) != len(expected) {
+			t.Errorf("expected %d headers, got %v", len(expected), msg.Header)
+		}
+		if msg.ID != event.ID || msg.Key != "customer-1" || string(msg.Data) != `{"order":1}` {
+			t.Errorf("expected the event ID, key and data, got %+v", msg)
+		}
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		msg, err := event.Message(StructuredMode)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if msg.Header["Content-Type"] != ContentTypeCloudEvent {
+			t.Errorf("expected content type %s, got %s", ContentTypeCloudEvent, msg.Header["Content-Type"])
+		}
+
+		var decoded map[string]any
+		if err := json.Unmarshal(msg.Data, &decoded); err != nil {
+			t.Fatalf("expected a JSON event, got %v", err)
+		}
+		if decoded["specversion"] != "1.0" || decoded["id"] != event.ID || decoded["subject"] != "order 1" ||
+			decoded["partitionkey"] != "customer-1" || decoded["datacontenttype"] != "application/json" {
+			t.Errorf("unexpected attributes %v", decoded)
+		}
+		if data, ok := decoded["data"].(map[string]any); !ok || data["order"] != float64(1) {
+			t.Errorf("expected the JSON data to be embedded, got %v", decoded["data"])
+		}
+	})
+
+	t.Run("binary data", func(t *testing.T) {
+		event := NewCloudEvent("/files", "com.example.file", []byte{0xff, 0x00})
+		event.DataContentType = "application/octet-stream"
+
+		msg, err := event.Message(StructuredMode)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var decoded map[string]any
+		json.Unmarshal(msg.Data, &decoded)
+		if decoded["data_base64"] != "/wA=" || decoded["data"] != nil {
+			t.Errorf("expected base64 data, got %v", decoded)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := []*CloudEvent{
+			{Source: "/orders", Type: "created"},
+			{ID: "1", Source: "/orders", Type: "created", Extensions: map[string]string{"Upper": "x"}},
+			{ID: "1", Source: "/orders", Type: "created", Extensions: map[string]string{"id": "x"}},
+		}
+		for _, event := range invalid {
+			for _, mode := range []CloudEventMode{BinaryMode, StructuredMode} {
+				if _, err := event.Message(mode); err == nil {
+					t.Errorf("expected an error for %+v in %s mode", event, mode)
+				}
+			}
+		}
+	})
+}
+
+// TestCloudEventBatch tests encoding events in batch mode.
+func TestCloudEventBatch(t *testing.T) {
+	events := []*CloudEvent{
+		NewCloudEvent("/orders", "created", []byte(`{"order":1}`)),
+		NewCloudEvent("/orders", "created", []byte(`{"order":2}`)),
+	}
+
+	msg, err := CloudEventBatch(events)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if msg.ID == "" || msg.Header["Content-Type"] != ContentTypeCloudEventBatch {
+		t.Errorf("expected an ID and content type %s, got %+v", ContentTypeCloudEventBatch, msg)
+	}
+
+	var decoded []map[string]any
+	if err := json.Unmarshal(msg.Data, &decoded); err != nil || len(decoded) != 2 {
+		t.Fatalf("expected a JSON array of 2 events, got %s", msg.Data)
+	}
+	if decoded[1]["id"] != events[1].ID {
+		t.Errorf("expected the events in order, got %v", decoded)
+	}
+}
+
+// TestEncodeHeader tests percent-encoding of binary mode header values.
+func TestEncodeHeader(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		"with space": "with%20space",
+		`"quoted"`:   "%22quoted%22",
+		"100%":       "100%25",
+		"été":        "%C3%A9t%C3%A9",
+	}
+	for value, expected := range tests {
+		if encoded := encodeHeader(value); encoded != expected {
+			t.Errorf("expected %q for %q, got %q", expected, value, encoded)
+		}
+	}
+}
+
+// TestEmitEvent tests sending an event in binary mode over the REST transport.
+func TestEmitEvent(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	clb := New(&Options{EndPoints: []string{server.URL}})
+	defer clb.Close()
+
+	event := NewCloudEvent("/orders", "com.example.order.created", []byte(`{"order":1}`))
+	id, err := clb.EmitEvent(context.Background(), event)
+	if err != nil || id != event.ID {
+		t.Fatalf("expected the event ID and no error, got %s, %v", id, err)
+	}
+
+	select {
+	case r := <-requests:
+		if r.Header.Get("Ce-Id") != event.ID || r.Header.Get("Ce-Type") != event.Type || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected the event attributes as headers, got %v", r.Header)
+		}
+		if body := <-bodies; string(body) != `{"order":1}` {
+			t.Errorf("expected the event data as body, got %s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the event to be sent")
+	}
+}
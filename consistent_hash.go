@@ -0,0 +1,116 @@
+package callback
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// hashRing maps keys to workers by consistent hashing. Every worker is placed on
+// the ring at several virtual nodes, so keys are spread evenly and adding or removing
+// a worker only remaps the keys of its own nodes. A ring is immutable once built.
+type hashRing struct {
+	hashes  []uint64  // Sorted hashes of the virtual nodes.
+	workers []*Worker // Worker of the virtual node at the same index.
+	size    int       // Number of distinct workers on the ring.
+}
+
+// newHashRing builds a ring with the given number of virtual nodes per worker.
+func newHashRing(workers []*Worker, virtualNodes int) *hashRing {
+	type node struct {
+		hash   uint64
+		worker *Worker
+	}
+
+	nodes := make([]node, 0, len(workers)*virtualNodes)
+	for _, worker := range workers {
+		for i := 0; i < virtualNodes; i++ {
+			nodes = append(nodes, node{hashKey(worker.point + "#" + strconv.Itoa(i)), worker})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].hash < nodes[j].hash })
+
+	ring := &hashRing{
+		hashes:  make([]uint64, len(nodes)),
+		workers: make([]*Worker, len(nodes)),
+		size:    len(workers),
+	}
+	for i, node := range nodes {
+		ring.hashes[i] = node.hash
+		ring.workers[i] = node.worker
+	}
+	return ring
+}
+
+// lookup returns the worker owning the key: the first available worker at or after the
+// hash of the key, going clockwise. Blocked workers fall through to the next node, so
+// their keys move to the following workers only while they are blocked. It returns nil
+// if all workers are blocked.
+func (r *hashRing) lookup(key string) *Worker {
+	if len(r.hashes) == 0 {
+		return nil
+	}
+
+	hash := hashKey(key)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+
+	// Check every distinct worker at most once.
+	checked := make(map[*Worker]struct{}, r.size)
+	for i := 0; i < len(r.hashes) && len(checked) < r.size; i++ {
+		worker := r.workers[(start+i)%len(r.hashes)]
+		if _, ok := checked[worker]; ok {
+			continue
+		}
+		checked[worker] = struct{}{}
+
+		if worker.available() {
+			return worker
+		}
+	}
+	return nil
+}
+
+// hashKey hashes a key onto the ring with FNV-1a, followed by a finalizer that spreads
+// the hashes of similar keys, such as the virtual nodes of one endpoint.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// rebuildRing rebuilds the hash ring from the current endpoints in ConsistentHash mode.
+// It must be called with c.mu held whenever the endpoints change.
+func (c *Callback) rebuildRing() {
+	if c.deliveryMode != ConsistentHash {
+		return
+	}
+	c.ring.Store(newHashRing(c.endPoints, c.virtualNodes))
+}
+
+// consistentHash sends the message to the worker owning its key on the hash ring.
+// If all workers are blocked, it returns an error indicating unavailability.
+func (c *Callback) consistentHash(msg *message) error {
+	return c.enqueueNext(msg, func() *Worker { return c.nextHash(msg) })
+}
+
+// nextHash returns the available worker owning the key of the message, or nil if all
+// workers are blocked. Messages without a key are hashed by their ID.
+func (c *Callback) nextHash(msg *message) *Worker {
+	ring := c.ring.Load()
+	if ring == nil {
+		return nil
+	}
+
+	key := msg.key
+	if key == "" {
+		key = msg.id
+	}
+	return ring.lookup(key)
+}
@@ -0,0 +1,152 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// hashCallback creates a callback in ConsistentHash mode with unstarted workers for the hosts.
+func hashCallback(hosts ...string) *Callback {
+	callback := &Callback{deliveryMode: ConsistentHash, virtualNodes: 100}
+	for _, host := range hosts {
+		callback.endPoints = append(callback.endPoints, &Worker{point: host, messageQueue: make(chan *message, 1)})
+	}
+	callback.rebuildRing()
+	return callback
+}
+
+// owners maps n keys to the endpoints owning them.
+func owners(callback *Callback, n int) map[string]string {
+	result := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("customer-%d", i)
+		if worker := callback.nextHash(&message{key: key}); worker != nil {
+			result[key] = worker.point
+		}
+	}
+	return result
+}
+
+// TestConsistentHash tests the mapping of keys to workers on the hash ring.
+func TestConsistentHash(t *testing.T) {
+	t.Run("same key same worker", func(t *testing.T) {
+		callback := hashCallback("a", "b", "c")
+
+		first := owners(callback, 100)
+		second := owners(callback, 100)
+		for key, point := range first {
+			if second[key] != point {
+				t.Errorf("expected key %s to stay on %s, got %s", key, point, second[key])
+			}
+		}
+	})
+
+	t.Run("even spread", func(t *testing.T) {
+		callback := hashCallback("a", "b", "c", "d")
+
+		counts := make(map[string]int)
+		for _, point := range owners(callback, 10000) {
+			counts[point]++
+		}
+		for _, point := range []string{"a", "b", "c", "d"} {
+			if counts[point] < 1500 || counts[point] > 3500 {
+				t.Errorf("expected about 2500 keys on %s, got %d", point, counts[point])
+			}
+		}
+	})
+
+	t.Run("minimal remapping", func(t *testing.T) {
+		before := owners(hashCallback("a", "b", "c", "d"), 10000)
+		after := owners(hashCallback("a", "b", "c", "d", "e"), 10000)
+
+		// Only keys moving to the new endpoint may change their owner.
+		moved := 0
+		for key, point := range before {
+			if after[key] != point {
+				if after[key] != "e" {
+					t.Fatalf("expected key %s to move only to e, got %s", key, after[key])
+				}
+				moved++
+			}
+		}
+		if moved > 3000 {
+			t.Errorf("expected about a fifth of the keys to move, got %d", moved)
+		}
+	})
+
+	t.Run("blocked worker falls through", func(t *testing.T) {
+		callback := hashCallback("a", "b", "c")
+		before := owners(callback, 1000)
+
+		callback.endPoints[0].blockedUntil = time.Now().Add(time.Minute)
+		during := owners(callback, 1000)
+		for key, point := range before {
+			if point == "a" && during[key] == "a" {
+				t.Fatalf("expected key %s to leave the blocked worker", key)
+			}
+			if point != "a" && during[key] != point {
+				t.Fatalf("expected key %s to stay on %s, got %s", key, point, during[key])
+			}
+		}
+
+		// The keys return once the worker is available again.
+		callback.endPoints[0].blockedUntil = time.Time{}
+		for key, point := range owners(callback, 1000) {
+			if before[key] != point {
+				t.Fatalf("expected key %s to return to %s, got %s", key, before[key], point)
+			}
+		}
+	})
+
+	t.Run("all blocked", func(t *testing.T) {
+		callback := hashCallback("a")
+		callback.endPoints[0].blockedUntil = time.Now().Add(time.Minute)
+
+		err := callback.consistentHash(newMessage(context.Background(), []byte("test data")))
+		if !errors.Is(err, ErrAllBlocked) {
+			t.Errorf("expected ErrAllBlocked, got %v", err)
+		}
+	})
+}
+
+// TestConsistentHash_Emit tests that messages with the same key are sent to the same endpoint.
+func TestConsistentHash_Emit(t *testing.T) {
+	clb := New(&Options{
+		Transport:    &testTransport{},
+		DeliveryMode: ConsistentHash,
+		EndPoints:    []string{"a", "b", "c"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) { results <- data })
+
+	var point string
+	for i := 0; i < 10; i++ {
+		if _, err := clb.EmitMessage(context.Background(), &Message{Key: "customer-1", Data: []byte("test data")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		data := waitData(t, results)
+		if point == "" {
+			point = data.Point
+		}
+		if data.Point != point {
+			t.Errorf("expected endpoint %s, got %s", point, data.Point)
+		}
+	}
+
+	// Removing another endpoint does not move the key.
+	for _, host := range []string{"a", "b", "c"} {
+		if host != point {
+			clb.DeleteEndpoint(host)
+			break
+		}
+	}
+	clb.EmitMessage(context.Background(), &Message{Key: "customer-1", Data: []byte("test data")})
+	if data := waitData(t, results); data.Point != point {
+		t.Errorf("expected endpoint %s, got %s", point, data.Point)
+	}
+}
@@ -0,0 +1,203 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrLetterNotFound is returned when a dead-lettered message does not exist.
+var ErrLetterNotFound = errors.New("dead letter not found")
+
+// Letter is a message that was dead-lettered after exhausting its retries.
+type Letter struct {
+	ID       string            `json:"id"`
+	Header   map[string]string `json:"header,omitempty"`
+	Key      string            `json:"key,omitempty"`
+	Data     []byte            `json:"data"`
+	Attempts []Attempt         `json:"attempts"`
+	Time     time.Time         `json:"time"`
+}
+
+// DeadLetter is a sink for messages that exhausted their retries.
+// Implementations must be safe for concurrent use.
+type DeadLetter interface {
+
+	// Put stores a dead-lettered message, replacing one with the same ID.
+	Put(letter *Letter) error
+
+	// List returns all dead-lettered messages, oldest first.
+	List() ([]*Letter, error)
+
+	// Get returns the dead-lettered message with the given ID or ErrLetterNotFound.
+	Get(id string) (*Letter, error)
+
+	// Delete removes the dead-lettered message with the given ID.
+	Delete(id string) error
+}
+
+// FileDeadLetter is a DeadLetter that stores every message as a JSON file in a directory.
+type FileDeadLetter struct {
+	dir string
+}
+
+// NewFileDeadLetter creates a FileDeadLetter in dir, creating the directory if needed.
+func NewFileDeadLetter(dir string) (*FileDeadLetter, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileDeadLetter{dir: dir}, nil
+}
+
+// Put writes the letter to its file atomically.
+func (d *FileDeadLetter) Put(letter *Letter) error {
+	content, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash never leaves a partial letter.
+	tmp, err := os.CreateTemp(d.dir, ".letter-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), d.path(letter.ID))
+}
+
+// List reads all letters in the directory, oldest first.
+func (d *FileDeadLetter) List() ([]*Letter, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*Letter, 0, len(entries))
+	for _, entry := range entries {
+		// Skip temporary files and anything else that is not a letter.
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		letter, err := d.read(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].Time.Before(letters[j].Time)
+	})
+	return letters, nil
+}
+
+// Get reads the letter with the given ID.
+func (d *FileDeadLetter) Get(id string) (*Letter, error) {
+	letter, err := d.read(d.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrLetterNotFound
+	}
+	return letter, err
+}
+
+// Delete removes the letter with the given ID. Deleting a missing letter is not an error.
+func (d *FileDeadLetter) Delete(id string) error {
+	err := os.Remove(d.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file of the letter with the given ID. The ID is escaped,
+// so caller-supplied IDs cannot point outside the directory.
+func (d *FileDeadLetter) path(id string) string {
+	return filepath.Join(d.dir, url.PathEscape(id)+".json")
+}
+
+// read decodes a letter file.
+func (d *FileDeadLetter) read(path string) (*Letter, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	letter := &Letter{}
+	if err := json.Unmarshal(content, letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// deadLetter stores a message that failed for good. It returns false if no
+// sink is configured or the message could not be stored.
+func (c *Callback) deadLetter(msg *message, data Data) bool {
+	if c.deadLetters == nil {
+		return false
+	}
+
+	err := c.deadLetters.Put(&Letter{
+		ID:       msg.id,
+		Header:   msg.header,
+		Key:      msg.key,
+		Data:     msg.data,
+		Attempts: data.Attempts,
+		Time:     time.Now(),
+	})
+	return err == nil
+}
+
+// DeadLetters returns all dead-lettered messages, oldest first.
+func (c *Callback) DeadLetters() ([]*Letter, error) {
+	if c.deadLetters == nil {
+		return nil, errors.New("dead letter sink is not configured")
+	}
+	return c.deadLetters.List()
+}
+
+// DeadLetter returns the dead-lettered message with the given ID.
+func (c *Callback) DeadLetter(id string) (*Letter, error) {
+	if c.deadLetters == nil {
+		return nil, errors.New("dead letter sink is not configured")
+	}
+	return c.deadLetters.Get(id)
+}
+
+// Redrive removes a dead-lettered message from the sink and emits it again with its
+// original ID and header. It is removed before the emit, so that a message that fails
+// again right away keeps its new letter; if it cannot be emitted, it is put back.
+func (c *Callback) Redrive(ctx context.Context, id string) (string, error) {
+	letter, err := c.DeadLetter(id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.deadLetters.Delete(letter.ID); err != nil {
+		return "", err
+	}
+
+	if _, err := c.EmitMessage(ctx, &Message{ID: letter.ID, Header: letter.Header, Key: letter.Key, Data: letter.Data}); err != nil {
+		if putErr := c.deadLetters.Put(letter); putErr != nil {
+			return "", errors.Join(err, putErr)
+		}
+		return "", err
+	}
+
+	return letter.ID, nil
+}
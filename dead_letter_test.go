@@ -0,0 +1,170 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestFileDeadLetter tests storing, listing, reading and deleting letters.
+func TestFileDeadLetter(t *testing.T) {
+	sink, err := NewFileDeadLetter(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	now := time.Now()
+	letters := []*Letter{
+		{ID: "tenant/2", Data: []byte("second"), Time: now},
+		{ID: "1", Data: []byte("first"), Time: now.Add(-time.Minute)},
+	}
+	for _, letter := range letters {
+		if err := sink.Put(letter); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// Letters are listed oldest first.
+	list, err := sink.List()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "1" || list[1].ID != "tenant/2" {
+		t.Fatalf("unexpected letters %+v", list)
+	}
+
+	letter, err := sink.Get("tenant/2")
+	if err != nil || string(letter.Data) != "second" {
+		t.Fatalf("expected letter tenant/2, got %+v %v", letter, err)
+	}
+
+	if err := sink.Delete("tenant/2"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := sink.Get("tenant/2"); !errors.Is(err, ErrLetterNotFound) {
+		t.Errorf("expected ErrLetterNotFound, got %v", err)
+	}
+}
+
+// TestDeadLetter_Redrive tests that a message exhausting its retries is dead-lettered
+// with every attempt and can be re-driven.
+func TestDeadLetter_Redrive(t *testing.T) {
+	sink, err := NewFileDeadLetter(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tr := &testTransport{err: errors.New("refused")}
+	clb := New(&Options{
+		Transport:  tr,
+		RetryMode:  Repeat,
+		RetryLimit: 2,
+		DeadLetter: sink,
+		EndPoints:  []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Request(context.Background(), []byte("payload")); err == nil {
+		t.Fatal("expected delivery to fail")
+	}
+	waitData(t, results)
+
+	letters, err := clb.DeadLetters()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(letters) != 1 || string(letters[0].Data) != "payload" {
+		t.Fatalf("expected the message to be dead-lettered, got %+v", letters)
+	}
+	if len(letters[0].Attempts) != 3 || letters[0].Attempts[0].Error == nil {
+		t.Errorf("expected 3 failed attempts, got %+v", letters[0].Attempts)
+	}
+
+	// The endpoint recovers and the message is re-driven with its original ID.
+	tr.mu.Lock()
+	tr.err = nil
+	tr.mu.Unlock()
+	clb.endPoints[0].Reset()
+
+	id, err := clb.Redrive(context.Background(), letters[0].ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != letters[0].ID {
+		t.Errorf("expected the original ID %s, got %s", letters[0].ID, id)
+	}
+
+	data := waitData(t, results)
+	if !data.Success || data.ID != id {
+		t.Errorf("expected successful redelivery, got %+v", data)
+	}
+	if _, err := clb.DeadLetter(id); !errors.Is(err, ErrLetterNotFound) {
+		t.Errorf("expected the letter to be removed, got %v", err)
+	}
+}
+
+// TestDeadLetter_RedriveFailure tests that a re-driven message is never lost from the sink.
+func TestDeadLetter_RedriveFailure(t *testing.T) {
+	sink, err := NewFileDeadLetter(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	clb := New(&Options{
+		Transport:  &testTransport{err: errors.New("refused")},
+		RetryMode:  Repeat,
+		RetryLimit: 1,
+		DeadLetter: sink,
+		EndPoints:  []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) { results <- data })
+
+	t.Run("fails again", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			clb.endPoints[0].Reset()
+			if _, err := clb.Request(context.Background(), []byte("payload")); err == nil {
+				t.Fatal("expected delivery to fail")
+			}
+			waitData(t, results)
+
+			letters, _ := clb.DeadLetters()
+			if len(letters) != 1 {
+				t.Fatalf("expected one letter, got %+v", letters)
+			}
+
+			// The re-driven message fails right away and must be dead-lettered again.
+			clb.endPoints[0].Reset()
+			if _, err := clb.Redrive(context.Background(), letters[0].ID); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			waitData(t, results)
+
+			if _, err := clb.DeadLetter(letters[0].ID); err != nil {
+				t.Fatalf("expected the message to be dead-lettered again, got %v", err)
+			}
+			sink.Delete(letters[0].ID)
+		}
+	})
+
+	t.Run("cannot be emitted", func(t *testing.T) {
+		letter := &Letter{ID: "1", Data: []byte("payload")}
+		sink.Put(letter)
+		clb.SyncEndPoint(nil)
+
+		if _, err := clb.Redrive(context.Background(), letter.ID); err == nil {
+			t.Fatal("expected an error without endpoints")
+		}
+		if _, err := clb.DeadLetter(letter.ID); err != nil {
+			t.Errorf("expected the letter to be put back, got %v", err)
+		}
+	})
+}
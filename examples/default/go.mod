@@ -1,7 +1,14 @@
 module github.com/gmelum/callback/examples/default
 
-go 1.23.2
+go 1.24
 
 require github.com/gmelum/callback v0.0.0-00010101000000-000000000000
 
+require (
+	github.com/quic-go/quic-go v0.59.0 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
 replace github.com/gmelum/callback => ../../
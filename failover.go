@@ -0,0 +1,45 @@
+package callback
+
+import (
+	"sort"
+)
+
+// failover sends the message to an available worker of the highest priority tier.
+// If all workers are blocked, it returns an error indicating unavailability.
+func (c *Callback) failover(msg *message) error {
+	return c.enqueueNext(msg, c.nextFailover)
+}
+
+// nextFailover returns the next available worker of the lowest tier that has one,
+// choosing among the workers of that tier in round-robin order. Lower tiers take the
+// traffic again as soon as one of their workers is available. It returns nil if all
+// workers are blocked.
+func (c *Callback) nextFailover() *Worker {
+	// Group a snapshot of the endpoints by tier, keeping their order within a tier.
+	tiers := make(map[int][]*Worker)
+	for _, worker := range c.workers() {
+		tiers[worker.priority] = append(tiers[worker.priority], worker)
+	}
+
+	order := make([]int, 0, len(tiers))
+	for tier := range tiers {
+		order = append(order, tier)
+	}
+	sort.Ints(order)
+
+	for _, tier := range order {
+		workers := tiers[tier]
+		start := c.roundRobinStart(len(workers))
+		for i := 0; i < len(workers); i++ {
+			if worker := workers[(start+i)%len(workers)]; worker.available() {
+				return worker
+			}
+		}
+	}
+	return nil
+}
+
+// priorityOf returns the configured tier of an endpoint, 0 if none is configured.
+func (c *Callback) priorityOf(host string) int {
+	return c.priorities[host]
+}
@@ -0,0 +1,97 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestFailover tests the selection of workers by priority tier.
+func TestFailover(t *testing.T) {
+	primary1 := &Worker{point: "a"}
+	primary2 := &Worker{point: "b"}
+	secondary := &Worker{point: "c", priority: 1}
+	dr := &Worker{point: "d", priority: 2}
+	callback := &Callback{endPoints: []*Worker{dr, primary1, secondary, primary2}}
+
+	t.Run("primary tier in round-robin order", func(t *testing.T) {
+		first := callback.nextFailover()
+		second := callback.nextFailover()
+		if first.priority != 0 || second.priority != 0 || first == second {
+			t.Errorf("expected the primary workers to take turns, got %s and %s", first.point, second.point)
+		}
+	})
+
+	t.Run("spill over", func(t *testing.T) {
+		primary1.blockedUntil = time.Now().Add(time.Minute)
+		if worker := callback.nextFailover(); worker != primary2 {
+			t.Fatalf("expected worker b while a is blocked, got %s", worker.point)
+		}
+
+		primary2.blockedUntil = time.Now().Add(time.Minute)
+		if worker := callback.nextFailover(); worker != secondary {
+			t.Fatalf("expected worker c while the primary tier is blocked, got %s", worker.point)
+		}
+
+		secondary.blockedUntil = time.Now().Add(time.Minute)
+		if worker := callback.nextFailover(); worker != dr {
+			t.Fatalf("expected worker d while the lower tiers are blocked, got %s", worker.point)
+		}
+	})
+
+	t.Run("return to primary", func(t *testing.T) {
+		primary1.blockedUntil = time.Time{}
+		for i := 0; i < 3; i++ {
+			if worker := callback.nextFailover(); worker != primary1 {
+				t.Errorf("expected worker a after it recovered, got %s", worker.point)
+			}
+		}
+	})
+
+	t.Run("all blocked", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", messageQueue: make(chan *message, 1), blockedUntil: time.Now().Add(time.Minute)},
+		}}
+
+		err := callback.failover(newMessage(context.Background(), []byte("test data")))
+		if !errors.Is(err, ErrAllBlocked) {
+			t.Errorf("expected ErrAllBlocked, got %v", err)
+		}
+	})
+}
+
+// TestFailover_Outage tests that the DR endpoint only takes traffic while the primary is down.
+func TestFailover_Outage(t *testing.T) {
+	clb := New(&Options{
+		Transport:    &flakyTransport{failures: map[string]int{"primary": 2}},
+		DeliveryMode: Failover,
+		EndPoints:    []string{"dr", "primary"},
+		Priorities:   map[string]int{"dr": 1},
+		RetryLimit:   1,
+		RetryTimeout: 50 * time.Millisecond,
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) { results <- data })
+
+	// The DR endpoint takes no traffic while the primary is available.
+	clb.Emit([]byte("test data"))
+	if data := waitData(t, results); data.Point != "primary" || data.Success {
+		t.Fatalf("expected a failure on primary, got %+v", data)
+	}
+
+	// The failures blocked the primary, so the DR endpoint takes over.
+	clb.Emit([]byte("test data"))
+	if data := waitData(t, results); data.Point != "dr" || !data.Success {
+		t.Fatalf("expected success on dr while the primary is blocked, got %+v", data)
+	}
+
+	// Traffic returns to the primary once its block period has passed.
+	time.Sleep(60 * time.Millisecond)
+	clb.Emit([]byte("test data"))
+	if data := waitData(t, results); data.Point != "primary" || !data.Success {
+		t.Fatalf("expected success on primary, got %+v", data)
+	}
+}
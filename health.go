@@ -0,0 +1,217 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// Healthy is the state of an endpoint that passes its active health checks.
+	Healthy State = "healthy"
+
+	// Unhealthy is the state of an endpoint that fails its active health checks.
+	// Unhealthy endpoints are taken out of rotation until they recover.
+	Unhealthy State = "unhealthy"
+)
+
+// HealthCheck configures active health checking of endpoints over HTTP.
+// Checks are sent through the REST transport and are disabled with other transports,
+// whose endpoints are not HTTP URLs.
+type HealthCheck struct {
+
+	// Path is appended to the endpoint address to build the health check URL.
+	// Default value: "/health"
+	Path string
+
+	// Method is the HTTP method of the health check request.
+	// Default value: http.MethodGet
+	Method string
+
+	// ExpectedStatus is the response status code of a healthy endpoint.
+	// Default value: http.StatusOK
+	ExpectedStatus int
+
+	// Interval is the period between health checks of an endpoint.
+	// Default value: time.Second * 10
+	Interval time.Duration
+
+	// Timeout bounds a single health check request.
+	// Default value: time.Second * 2
+	Timeout time.Duration
+
+	// HealthyThreshold is the number of consecutive passed checks that bring an unhealthy endpoint back into rotation.
+	// Default value: 2
+	HealthyThreshold int
+
+	// UnhealthyThreshold is the number of consecutive failed checks that take an endpoint out of rotation.
+	// Default value: 3
+	UnhealthyThreshold int
+}
+
+// EndpointHealth is the current health of an endpoint.
+type EndpointHealth struct {
+	Point   string    `json:"point"`
+	Healthy bool      `json:"healthy"`
+	Breaker State     `json:"breaker"`
+	Checked time.Time `json:"checked"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// defaultHealthCheck initializes default values for HealthCheck fields that are not set.
+func defaultHealthCheck(h *HealthCheck) *HealthCheck {
+
+	// Set default path to /health if none is specified
+	if h.Path == "" {
+		h.Path = "/health"
+	}
+
+	// Set default method to GET if none is specified
+	if h.Method == "" {
+		h.Method = http.MethodGet
+	}
+
+	// Set default expected status to 200 if none is specified
+	if h.ExpectedStatus == 0 {
+		h.ExpectedStatus = http.StatusOK
+	}
+
+	// Set default interval to 10 seconds if none is specified
+	if h.Interval == 0 {
+		h.Interval = time.Second * 10
+	}
+
+	// Set default timeout to 2 seconds if none is specified
+	if h.Timeout == 0 {
+		h.Timeout = time.Second * 2
+	}
+
+	// Set default healthy threshold to 2 if none is specified
+	if h.HealthyThreshold == 0 {
+		h.HealthyThreshold = 2
+	}
+
+	// Set default unhealthy threshold to 3 if none is specified
+	if h.UnhealthyThreshold == 0 {
+		h.UnhealthyThreshold = 3
+	}
+
+	return h
+}
+
+// healthCheck checks the worker's endpoint right away and then every interval until the worker is closed.
+func (w *Worker) healthCheck() {
+	ticker := time.NewTicker(w.callback.healthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.check()
+
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// check sends a single health check request and updates the worker's health.
+func (w *Worker) check() {
+	err := w.probe()
+
+	defer w.flush() // Report state transitions once the mutex is released.
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.checked = time.Now()
+	config := w.callback.healthCheck
+
+	if err != nil {
+		w.checkError = err.Error()
+		w.checkPasses = 0
+		w.checkFailures++
+		if !w.unhealthy && w.checkFailures >= config.UnhealthyThreshold {
+			w.setHealth(false)
+		}
+		return
+	}
+
+	w.checkError = ""
+	w.checkFailures = 0
+	w.checkPasses++
+	if w.unhealthy && w.checkPasses >= config.HealthyThreshold {
+		w.setHealth(true)
+	}
+}
+
+// probe sends the health check request to the worker's endpoint.
+func (w *Worker) probe() error {
+	config := w.callback.healthCheck
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, config.Method, strings.TrimRight(w.point, "/")+config.Path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Connect the way deliveries do
+	client, err := w.callback.healthREST.Client(w.point)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != config.ExpectedStatus {
+		return fmt.Errorf("received %d response code", resp.StatusCode)
+	}
+	return nil
+}
+
+// setHealth changes the health of the worker and records the transition, which is reported
+// by flush once w.mu is released. It must be called with w.mu held.
+func (w *Worker) setHealth(healthy bool) {
+	from, to := Healthy, Unhealthy
+	if healthy {
+		from, to = Unhealthy, Healthy
+	}
+	w.unhealthy = !healthy
+
+	w.transitions = append(w.transitions, Event{
+		Point: w.point,
+		Type:  HealthEvent,
+		From:  from,
+		To:    to,
+		Time:  time.Now(),
+	})
+}
+
+// Health returns the current health of every endpoint.
+func (c *Callback) Health() []EndpointHealth {
+	workers := c.workers()
+
+	result := make([]EndpointHealth, 0, len(workers))
+	for _, worker := range workers {
+		breaker := worker.State()
+
+		worker.mu.Lock()
+		result = append(result, EndpointHealth{
+			Point:   worker.point,
+			Healthy: !worker.unhealthy,
+			Breaker: breaker,
+			Checked: worker.checked,
+			Error:   worker.checkError,
+		})
+		worker.mu.Unlock()
+	}
+	return result
+}
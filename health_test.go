@@ -0,0 +1,165 @@
+package callback
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// TestDefaultHealthCheck tests that unset HealthCheck fields receive default values.
+func TestDefaultHealthCheck(t *testing.T) {
+	got := defaultOptions(&Options{HealthCheck: &HealthCheck{Path: "/ready"}}).HealthCheck
+	expected := HealthCheck{
+		Path:               "/ready",
+		Method:             http.MethodGet,
+		ExpectedStatus:     http.StatusOK,
+		Interval:           time.Second * 10,
+		Timeout:            time.Second * 2,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	}
+	if *got != expected {
+		t.Errorf("defaultOptions() HealthCheck = %+v, want %+v", *got, expected)
+	}
+}
+
+// TestHealthCheck tests that failing health checks take an endpoint out of rotation
+// and passing ones bring it back.
+func TestHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" || !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	clb := New(&Options{
+		EndPoints: []string{server.URL},
+		HealthCheck: &HealthCheck{
+			Interval:           time.Millisecond * 10,
+			HealthyThreshold:   1,
+			UnhealthyThreshold: 2,
+		},
+	})
+	defer clb.Close()
+
+	events := make(chan *Event, 10)
+	clb.OnEvent(func(event *Event) {
+		events <- event
+	})
+
+	// waitHealth waits for the endpoint to reach the given health.
+	waitHealth := func(expected bool) {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second * 5)
+		for clb.Health()[0].Healthy != expected {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for healthy = %v", expected)
+			}
+			time.Sleep(time.Millisecond * 5)
+		}
+	}
+
+	waitHealth(true)
+	if !clb.endPoints[0].available() {
+		t.Fatal("expected a healthy endpoint to be available")
+	}
+
+	// The endpoint goes down and is taken out of rotation.
+	healthy.Store(false)
+	waitHealth(false)
+	if clb.endPoints[0].available() {
+		t.Fatal("expected an unhealthy endpoint to be unavailable")
+	}
+	if health := clb.Health()[0]; health.Error == "" || health.Checked.IsZero() {
+		t.Errorf("expected the failed check to be reported, got %+v", health)
+	}
+	if _, err := clb.Emit([]byte("payload")); err == nil {
+		t.Error("expected Emit to fail without healthy endpoints")
+	}
+
+	// The endpoint recovers and returns to rotation.
+	healthy.Store(true)
+	waitHealth(true)
+	if !clb.endPoints[0].available() {
+		t.Fatal("expected a recovered endpoint to be available")
+	}
+
+	for _, expected := range []State{Unhealthy, Healthy} {
+		select {
+		case event := <-events:
+			if event.Type != HealthEvent || event.To != expected {
+				t.Errorf("expected health event to %s, got %+v", expected, event)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timed out waiting for health event to %s", expected)
+		}
+	}
+}
+
+// TestHealthCheck_TLS tests that health checks trust the CA configured for deliveries.
+func TestHealthCheck_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	clb := New(&Options{
+		EndPoints:   []string{server.URL},
+		TLS:         &transport.TLS{CAFile: caFile},
+		HealthCheck: &HealthCheck{Interval: time.Millisecond * 10},
+	})
+	defer clb.Close()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for clb.Health()[0].Checked.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a health check")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	if health := clb.Health()[0]; health.Error != "" {
+		t.Errorf("expected the check to pass, got %q", health.Error)
+	}
+}
+
+// TestHealthCheck_OtherTransport tests that health checks are disabled with transports
+// whose endpoints are not HTTP URLs.
+func TestHealthCheck_OtherTransport(t *testing.T) {
+	clb := New(&Options{
+		Transport: &testTransport{},
+		EndPoints: []string{"127.0.0.1:4433"},
+		HealthCheck: &HealthCheck{
+			Interval:           time.Millisecond * 10,
+			UnhealthyThreshold: 1,
+		},
+	})
+	defer clb.Close()
+
+	time.Sleep(time.Millisecond * 50)
+	if health := clb.Health()[0]; !health.Healthy || !health.Checked.IsZero() {
+		t.Errorf("expected the endpoint not to be checked, got %+v", health)
+	}
+	if !clb.endPoints[0].available() {
+		t.Error("expected the endpoint to stay in rotation")
+	}
+}
@@ -0,0 +1,231 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hedgeMinSamples is the number of observed latencies needed before the hedge delay
+// follows Hedge.Percentile instead of Hedge.Delay.
+const hedgeMinSamples = 10
+
+// Hedge configures hedged requests: if the request of a message has not completed within
+// the hedge delay, the message is also sent to a second endpoint and the first successful
+// response wins. The slower request is cancelled. Hedging trades duplicate deliveries for
+// lower latency, so receivers should deduplicate by message ID.
+type Hedge struct {
+
+	// Delay is the time after which a request is hedged. With Percentile set, it is used
+	// until enough latencies are observed; if it is zero then, requests are not hedged meanwhile.
+	// Without Percentile, a zero Delay sends every message to two endpoints at once.
+	Delay time.Duration
+
+	// Percentile (0..1), if set, hedges requests slower than this percentile of the
+	// latencies of recent successful requests, such as 0.95.
+	Percentile float64
+
+	// Samples is the number of recent latencies the percentile is computed over.
+	// Default value: 100
+	Samples int
+}
+
+// defaultHedge initializes default values for Hedge fields that are not set.
+func defaultHedge(h *Hedge) *Hedge {
+
+	// Set default number of latency samples to 100 if none is specified
+	if h.Samples == 0 {
+		h.Samples = 100
+	}
+
+	return h
+}
+
+// latencies keeps the latencies of recent successful requests in a ring buffer.
+type latencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// newLatencies creates a buffer for the given number of latencies.
+func newLatencies(size int) *latencies {
+	return &latencies{samples: make([]time.Duration, 0, size)}
+}
+
+// observe records a latency, replacing the oldest one once the buffer is full.
+func (l *latencies) observe(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) < cap(l.samples) {
+		l.samples = append(l.samples, latency)
+		return
+	}
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+// percentile returns the p-th percentile of the recorded latencies. It returns false
+// if fewer than hedgeMinSamples latencies were recorded.
+func (l *latencies) percentile(p float64) (time.Duration, bool) {
+	l.mu.Lock()
+	sorted := append([]time.Duration(nil), l.samples...)
+	l.mu.Unlock()
+
+	if len(sorted) < hedgeMinSamples {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// Use the nearest-rank method.
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	index = max(0, min(index, len(sorted)-1))
+	return sorted[index], true
+}
+
+// hedgeDelay returns the time after which a request is hedged, or false if requests
+// are not hedged.
+func (c *Callback) hedgeDelay() (time.Duration, bool) {
+	if c.hedge == nil {
+		return 0, false
+	}
+
+	if c.hedge.Percentile > 0 {
+		if delay, ok := c.latency.percentile(c.hedge.Percentile); ok {
+			return delay, true
+		}
+		return c.hedge.Delay, c.hedge.Delay > 0
+	}
+	return c.hedge.Delay, true
+}
+
+// observe records the latency of a successful request for the hedge percentile.
+func (c *Callback) observe(latency time.Duration) {
+	if c.latency != nil {
+		c.latency.observe(latency)
+	}
+}
+
+// hedgeTarget returns an available worker other than primary to hedge the message to,
+// chosen by the delivery mode, or nil if there is none. A probe slot of a half-open
+// worker is reserved in probe and must be freed once the hedged request completes.
+func (c *Callback) hedgeTarget(msg *message, primary *Worker, probe *bool) *Worker {
+	// Try at most once per worker, as the delivery mode may keep picking the primary.
+	for range c.workers() {
+		worker := c.next(msg)
+		if worker == nil {
+			return nil
+		}
+		if worker != primary && worker.reserve(probe) {
+			return worker
+		}
+	}
+	return nil
+}
+
+// outcome is the result of a request sent to the endpoint of a worker.
+type outcome struct {
+	worker *Worker
+	res    []byte
+	err    error
+}
+
+// request sends the message to the worker's endpoint, hedging it to a second endpoint
+// if enabled. It returns the worker whose request succeeded, or this worker and its error
+// if the message was not delivered. The attempts of the other requests are recorded in
+// the message and counted by their workers; the returned one is left to the caller.
+func (w *Worker) request(msg *message) (*Worker, []byte, error) {
+	// Messages of a broadcast group are bound to their endpoint and are never hedged.
+	delay, ok := w.callback.hedgeDelay()
+	if !ok || msg.group != nil {
+		start := time.Now()
+		res, err := w.handlerRequest(msg.ctx, msg)
+		if err == nil {
+			w.callback.observe(time.Since(start))
+		}
+		return w, res, err
+	}
+
+	// Cancel the request that is still running once the other one has succeeded.
+	ctx, cancel := context.WithCancel(msg.ctx)
+	defer cancel()
+
+	// The channel has room for both requests, so the loser never blocks.
+	outcomes := make(chan outcome, 2)
+	send := func(worker *Worker) {
+		start := time.Now()
+		res, err := worker.handlerRequest(ctx, msg)
+		if err == nil {
+			w.callback.observe(time.Since(start))
+		}
+		outcomes <- outcome{worker, res, err}
+	}
+	go send(w)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	running := map[*Worker]bool{w: true}
+	var failures []outcome
+	for len(running) > 0 {
+		select {
+		case o := <-outcomes:
+			delete(running, o.worker)
+			if o.err != nil {
+				failures = append(failures, o)
+				continue
+			}
+
+			// The first success wins: record the failed and the cancelled requests.
+			w.fail(msg, failures)
+			for worker := range running {
+				msg.attempts = append(msg.attempts, Attempt{Point: worker.point, Error: &Error{
+					Code:     0,
+					Message:  "[ERROR] cancelled after another endpoint answered first",
+					Critical: false,
+				}})
+			}
+			return o.worker, o.res, nil
+
+		case <-timer.C:
+			// The request is slow: hedge it to a second endpoint, if there is one.
+			var probe bool
+			if hedge := w.callback.hedgeTarget(msg, w, &probe); hedge != nil {
+				running[hedge] = true
+				go func() {
+					defer hedge.free(&probe)
+					send(hedge)
+				}()
+			}
+		}
+	}
+
+	// Both requests failed: return the failure of this worker to the caller.
+	var own error
+	var others []outcome
+	for _, o := range failures {
+		if o.worker == w {
+			own = o.err
+			continue
+		}
+		others = append(others, o)
+	}
+	w.fail(msg, others)
+	return w, nil, own
+}
+
+// fail records the failed requests of a hedged message and counts them against their workers.
+func (w *Worker) fail(msg *message, failures []outcome) {
+	for _, o := range failures {
+		o.worker.Inc()
+		msg.attempts = append(msg.attempts, Attempt{Point: o.worker.point, Error: &Error{
+			Code:     0,
+			Message:  fmt.Sprintf("[ERROR] %v", o.err.Error()),
+			Critical: true,
+		}})
+	}
+}
@@ -0,0 +1,162 @@
+package callback
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// hangTransport is a Transport whose "slow" endpoint answers only when its request is cancelled.
+type hangTransport struct {
+	mu        sync.Mutex
+	cancelled int
+}
+
+func (t *hangTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	if endpoint == "slow" {
+		<-ctx.Done()
+
+		t.mu.Lock()
+		t.cancelled++
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	return []byte(endpoint), nil
+}
+
+func (t *hangTransport) Close() error {
+	return nil
+}
+
+// TestLatencies tests the percentile of recorded latencies.
+func TestLatencies(t *testing.T) {
+	t.Run("too few samples", func(t *testing.T) {
+		l := newLatencies(100)
+		for i := 1; i < hedgeMinSamples; i++ {
+			l.observe(time.Duration(i) * time.Millisecond)
+		}
+		if _, ok := l.percentile(0.95); ok {
+			t.Error("expected no percentile with too few samples")
+		}
+	})
+
+	t.Run("nearest rank", func(t *testing.T) {
+		l := newLatencies(100)
+		for i := 100; i >= 1; i-- {
+			l.observe(time.Duration(i) * time.Millisecond)
+		}
+		if p, _ := l.percentile(0.95); p != 95*time.Millisecond {
+			t.Errorf("expected 95ms, got %v", p)
+		}
+		if p, _ := l.percentile(1); p != 100*time.Millisecond {
+			t.Errorf("expected 100ms, got %v", p)
+		}
+	})
+
+	t.Run("oldest replaced", func(t *testing.T) {
+		l := newLatencies(hedgeMinSamples)
+		for i := 0; i < hedgeMinSamples; i++ {
+			l.observe(time.Second)
+		}
+		for i := 0; i < hedgeMinSamples; i++ {
+			l.observe(time.Millisecond)
+		}
+		if p, _ := l.percentile(1); p != time.Millisecond {
+			t.Errorf("expected 1ms, got %v", p)
+		}
+	})
+}
+
+// TestHedgeDelay tests the choice between the fixed and the percentile delay.
+func TestHedgeDelay(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		if _, ok := (&Callback{}).hedgeDelay(); ok {
+			t.Error("expected no hedging")
+		}
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		callback := &Callback{hedge: &Hedge{Delay: time.Second}}
+		if delay, ok := callback.hedgeDelay(); !ok || delay != time.Second {
+			t.Errorf("expected 1s, got %v", delay)
+		}
+	})
+
+	t.Run("percentile", func(t *testing.T) {
+		callback := &Callback{hedge: &Hedge{Percentile: 0.5}, latency: newLatencies(100)}
+		if _, ok := callback.hedgeDelay(); ok {
+			t.Error("expected no hedging before enough latencies are observed")
+		}
+
+		callback.hedge.Delay = time.Second
+		if delay, ok := callback.hedgeDelay(); !ok || delay != time.Second {
+			t.Errorf("expected the fixed delay of 1s meanwhile, got %v", delay)
+		}
+
+		for i := 0; i < hedgeMinSamples; i++ {
+			callback.observe(time.Millisecond)
+		}
+		if delay, ok := callback.hedgeDelay(); !ok || delay != time.Millisecond {
+			t.Errorf("expected 1ms, got %v", delay)
+		}
+	})
+}
+
+// TestHedge tests that a slow request is hedged to a second endpoint.
+func TestHedge(t *testing.T) {
+	tr := &hangTransport{}
+	clb := New(&Options{
+		Transport: tr,
+		EndPoints: []string{"slow", "fast"},
+		Hedge:     &Hedge{Delay: 10 * time.Millisecond},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) { results <- data })
+
+	// The first message goes to the slow endpoint and the hedge to the fast one wins.
+	if _, err := clb.Emit([]byte("test data")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := waitData(t, results)
+	if !data.Success || data.Point != "fast" {
+		t.Fatalf("expected success on fast, got %+v", data)
+	}
+	if len(data.Attempts) != 2 || data.Attempts[0].Point != "slow" || data.Attempts[0].Error == nil ||
+		data.Attempts[1].Point != "fast" || data.Attempts[1].Error != nil {
+		t.Errorf("expected a cancelled attempt on slow and a successful one on fast, got %v", data.Attempts)
+	}
+
+	// The losing request is cancelled and is not counted as a failure.
+	deadline := time.Now().Add(time.Second)
+	for {
+		tr.mu.Lock()
+		cancelled := tr.cancelled
+		tr.mu.Unlock()
+		if cancelled == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the slow request to be cancelled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	worker := clb.workers()[0]
+	worker.mu.Lock()
+	failures := len(worker.errorTimestamps)
+	worker.mu.Unlock()
+	if failures != 0 {
+		t.Error("expected the cancelled request not to count against the slow endpoint")
+	}
+
+	// A fast request is not hedged.
+	clb.DeleteEndpoint("slow")
+	clb.Emit([]byte("test data"))
+	if data := waitData(t, results); len(data.Attempts) != 1 {
+		t.Errorf("expected a single attempt, got %v", data.Attempts)
+	}
+}
@@ -0,0 +1,57 @@
+package callback
+
+import (
+	"math/rand/v2"
+)
+
+// sampleThreshold is the number of available workers above which LeastOutstanding mode
+// compares two randomly sampled workers instead of scanning all of them.
+const sampleThreshold = 8
+
+// leastOutstanding sends the message to the available worker with the fewest outstanding
+// messages. If all workers are blocked, it returns an error indicating unavailability.
+func (c *Callback) leastOutstanding(msg *message) error {
+	return c.enqueueNext(msg, c.nextLeastOutstanding)
+}
+
+// nextLeastOutstanding returns the available worker with the fewest queued and in-flight
+// messages. Small pools are scanned starting after the last selected worker, so ties are
+// broken in round-robin order. Large pools use power-of-two-choices sampling: two random
+// workers are compared and the less loaded one is taken, which avoids sending every
+// message to the same momentarily idle worker. It returns nil if all workers are blocked.
+func (c *Callback) nextLeastOutstanding() *Worker {
+	// Take a snapshot of the endpoints and keep only the available ones.
+	var workers []*Worker
+	for _, worker := range c.workers() {
+		if worker.available() {
+			workers = append(workers, worker)
+		}
+	}
+
+	switch {
+	case len(workers) == 0:
+		return nil
+	case len(workers) > sampleThreshold:
+		// Pick two distinct workers at random.
+		i := rand.IntN(len(workers))
+		j := rand.IntN(len(workers) - 1)
+		if j >= i {
+			j++
+		}
+		if workers[j].outstanding.Load() < workers[i].outstanding.Load() {
+			return workers[j]
+		}
+		return workers[i]
+	}
+
+	// Scan all workers starting at the round-robin position.
+	start := c.roundRobinStart(len(workers))
+	best := workers[start]
+	for i := 1; i < len(workers); i++ {
+		worker := workers[(start+i)%len(workers)]
+		if worker.outstanding.Load() < best.outstanding.Load() {
+			best = worker
+		}
+	}
+	return best
+}
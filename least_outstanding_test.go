@@ -0,0 +1,133 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// TestLeastOutstanding tests the selection of the least loaded worker.
+func TestLeastOutstanding(t *testing.T) {
+	t.Run("fewest outstanding", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{{point: "a"}, {point: "b"}, {point: "c"}}}
+		callback.endPoints[0].outstanding.Store(3)
+		callback.endPoints[1].outstanding.Store(1)
+		callback.endPoints[2].outstanding.Store(2)
+
+		for i := 0; i < 3; i++ {
+			if worker := callback.nextLeastOutstanding(); worker.point != "b" {
+				t.Errorf("expected worker b, got %s", worker.point)
+			}
+		}
+	})
+
+	t.Run("ties in round-robin order", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{{point: "a"}, {point: "b"}}}
+
+		first := callback.nextLeastOutstanding()
+		second := callback.nextLeastOutstanding()
+		if first == second {
+			t.Errorf("expected idle workers to take turns, got %s twice", first.point)
+		}
+	})
+
+	t.Run("skips blocked", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", blockedUntil: time.Now().Add(time.Minute)},
+			{point: "b"},
+		}}
+		callback.endPoints[1].outstanding.Store(5)
+
+		if worker := callback.nextLeastOutstanding(); worker.point != "b" {
+			t.Errorf("expected worker b, got %s", worker.point)
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		callback := &Callback{}
+		for i := 0; i <= sampleThreshold; i++ {
+			worker := &Worker{point: string(rune('a' + i))}
+			worker.outstanding.Store(1)
+			callback.endPoints = append(callback.endPoints, worker)
+		}
+		// The worker under the heaviest load must never win a comparison.
+		callback.endPoints[0].outstanding.Store(100)
+
+		for i := 0; i < 100; i++ {
+			if worker := callback.nextLeastOutstanding(); worker.point == "a" {
+				t.Fatal("expected the busiest worker never to be picked")
+			}
+		}
+	})
+
+	t.Run("all blocked", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", messageQueue: make(chan *message, 1), blockedUntil: time.Now().Add(time.Minute)},
+		}}
+
+		err := callback.leastOutstanding(newMessage(context.Background(), []byte("test data")))
+		if !errors.Is(err, ErrAllBlocked) {
+			t.Errorf("expected ErrAllBlocked, got %v", err)
+		}
+	})
+}
+
+// slowTransport answers requests to the slow endpoint only after release is closed.
+type slowTransport struct {
+	release chan struct{}
+
+	mu   sync.Mutex
+	sent map[string]int
+}
+
+func (s *slowTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	s.mu.Lock()
+	s.sent[endpoint]++
+	s.mu.Unlock()
+
+	if endpoint == "slow" {
+		<-s.release
+	}
+	return []byte("ok"), nil
+}
+
+func (s *slowTransport) Close() error { return nil }
+
+// TestLeastOutstanding_SlowEndpoint tests that a slow endpoint receives less traffic.
+func TestLeastOutstanding_SlowEndpoint(t *testing.T) {
+	tr := &slowTransport{release: make(chan struct{}), sent: make(map[string]int)}
+	clb := New(&Options{
+		Transport:    tr,
+		DeliveryMode: LeastOutstanding,
+		EndPoints:    []string{"slow", "fast"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 100)
+	clb.On(func(data *Data) { results <- data })
+
+	for i := 0; i < 20; i++ {
+		if _, err := clb.Emit([]byte("test data")); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		// Wait for messages sent to the fast endpoint to complete.
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(tr.release)
+	for i := 0; i < 20; i++ {
+		waitData(t, results)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.sent["slow"] > 2 {
+		t.Errorf("expected the slow endpoint to get at most 2 messages, got %d", tr.sent["slow"])
+	}
+	if tr.sent["fast"]+tr.sent["slow"] != 20 {
+		t.Errorf("expected 20 messages to be sent, got %v", tr.sent)
+	}
+}
@@ -0,0 +1,39 @@
+package callback
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// Message is an envelope for a payload emitted with EmitMessage.
+type Message struct {
+
+	// ID identifies the message in the Data passed to the On handler and is sent
+	// to the receiver, so both sides can correlate and deduplicate deliveries.
+	// If empty, a random ID is generated.
+	ID string
+
+	// Header holds metadata of the message, such as tenant, event type or trace IDs.
+	// The REST transport forwards it as HTTP headers, other transports map it to their
+	// own metadata. It is echoed back in the Data passed to the On handler.
+	Header map[string]string
+
+	// Key is the partition key of the message. In ConsistentHash delivery mode,
+	// all messages with the same key are sent to the same endpoint.
+	Key string
+
+	// Data is the payload of the message.
+	Data []byte
+}
+
+// newID generates a random version 4 UUID used as a message ID.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	// Set the version (4) and variant (RFC 4122) bits.
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
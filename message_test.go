@@ -0,0 +1,102 @@
+package callback
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+// TestNewID tests that generated IDs are unique version 4 UUIDs.
+func TestNewID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		id := newID()
+		if !pattern.MatchString(id) {
+			t.Fatalf("expected a version 4 UUID, got %s", id)
+		}
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate ID %s", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+// TestEmit_MessageID tests that the message ID is returned by Emit, passed to the
+// transport and reported in the Data passed to the On handler.
+func TestEmit_MessageID(t *testing.T) {
+	tr := &testTransport{}
+	clb := New(&Options{
+		Transport: tr,
+		EndPoints: []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	// A generated ID.
+	id, err := clb.Emit([]byte("payload"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id == "" {
+		t.Fatal("expected a generated message ID")
+	}
+	if data := waitData(t, results); data.ID != id {
+		t.Errorf("expected Data ID %s, got %s", id, data.ID)
+	}
+
+	// A caller-supplied ID.
+	id, err = clb.EmitMessage(context.Background(), &Message{ID: "order-42", Data: []byte("payload")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if id != "order-42" {
+		t.Errorf("expected caller-supplied ID, got %s", id)
+	}
+	if data := waitData(t, results); data.ID != "order-42" {
+		t.Errorf("expected Data ID order-42, got %s", data.ID)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.ids) != 2 || tr.ids[1] != "order-42" {
+		t.Errorf("expected the transport to receive the message IDs, got %v", tr.ids)
+	}
+}
+
+// TestEmit_MessageHeader tests that the message header is passed to the transport
+// and echoed back in the Data passed to the On handler.
+func TestEmit_MessageHeader(t *testing.T) {
+	tr := &testTransport{}
+	clb := New(&Options{
+		Transport: tr,
+		EndPoints: []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	header := map[string]string{"X-Tenant": "acme", "X-Event-Type": "order.created"}
+	if _, err := clb.EmitMessage(context.Background(), &Message{Header: header, Data: []byte("payload")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if data.Header["X-Tenant"] != "acme" || data.Header["X-Event-Type"] != "order.created" {
+		t.Errorf("expected header to be echoed back, got %v", data.Header)
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.header["X-Tenant"] != "acme" {
+		t.Errorf("expected the transport to receive the header, got %v", tr.header)
+	}
+}
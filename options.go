@@ -1,6 +1,10 @@
 package callback
 
-import "time"
+import (
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
 
 // DeliveryMode defines the method for delivering messages to clients.
 // It can be used to select a notification delivery strategy,
@@ -17,22 +21,68 @@ var (
 	// Broadcast specifies a message delivery mode that sends to all connected clients at once.
 	// Each notification is broadcast immediately to all clients connected to the server.
 	// This mode ensures that every client receives the same message at the same time.
+	// After the per-endpoint results, an aggregate Data with a Summary is passed to the On handler.
 	Broadcast DeliveryMode = "broadcast"
+
+	// Quorum specifies a message delivery mode that sends to all connected clients,
+	// but considers the message delivered once a quorum of clients acknowledges it.
+	// The quorum is configured with Options.Quorum or Options.QuorumRatio and defaults to a majority.
+	Quorum DeliveryMode = "quorum"
+
+	// WeightedRoundRobin specifies a message delivery mode to one of the connected clients,
+	// where each client receives a share of the messages proportional to its weight.
+	// Messages are spread smoothly over the cycle, as in nginx. The share of blocked clients
+	// is redistributed among the others. Weights are set with Options.Weights, AddEndpoint
+	// and SyncWeightedEndPoint.
+	WeightedRoundRobin DeliveryMode = "weighted_round_robin"
+
+	// LeastOutstanding specifies a message delivery mode to the connected client with the
+	// fewest queued and in-flight messages, so slow clients naturally receive less traffic.
+	// Large pools compare two randomly sampled clients instead of all of them.
+	LeastOutstanding DeliveryMode = "least_outstanding"
+
+	// ConsistentHash specifies a message delivery mode to the client owning the key of the
+	// message on a consistent hash ring, so all messages with the same Message.Key go to the
+	// same client. Changing the endpoints only remaps the keys of the added or removed clients.
+	// While a client is blocked, its keys fall through to the next client on the ring.
+	// Messages without a key are spread by their ID.
+	ConsistentHash DeliveryMode = "consistent_hash"
+
+	// Failover specifies a message delivery mode where clients are organized into priority
+	// tiers with Options.Priorities. All messages go to tier 0, using the Round Robin
+	// algorithm within the tier, and only spill over to the next tier while every client
+	// of the lower tiers is blocked. Traffic returns to the primary tier once it recovers.
+	Failover DeliveryMode = "failover"
 )
 
-// Transport defines the transport protocol used for message delivery.
-type Transport string
+// Transport defines the transport used for message delivery.
+// Any implementation of transport.Transport can be passed in Options
+// to plug in a custom protocol; REST and QUIC are built in.
+type Transport = transport.Transport
 
 var (
 	// REST is the transport protocol that uses RESTful API for message delivery.
 	// This is typically over HTTP and suitable for stateless communication.
-	REST Transport = "REST"
+	// Every callback using it gets its own transport.REST configured with the Options.
+	REST Transport = transport.NewREST()
 
 	// QUIC is the transport protocol that uses QUIC (Quick UDP Internet Connections) for message delivery.
 	// It provides low-latency, secure transport and is typically faster than traditional HTTP/HTTPS protocols.
-	QUIC Transport = "QUIC"
+	// Endpoints are given as "host:port" or "quic://host:port"; see transport.QUIC for the wire format.
+	// Every callback using it gets its own connection pool. Use transport.NewQUIC directly to configure TLS.
+	QUIC Transport = transport.NewQUIC(nil)
 )
 
+// Signing configures HMAC signing of requests sent by the REST transport.
+// See transport.Signing and transport.Verify.
+type Signing = transport.Signing
+
+// TLS configures the TLS connections of the REST transport. See transport.TLS.
+type TLS = transport.TLS
+
+// HTTP configures the HTTP client of the REST transport. See transport.HTTP.
+type HTTP = transport.HTTP
+
 // RetryMode defines how retry logic is handled when sending messages to endpoints.
 type RetryMode string
 
@@ -47,22 +97,68 @@ var (
 // Options contains configuration options for the message delivery system.
 type Options struct {
 
-	// Transport defines the transport protocol used for message delivery.
+	// Transport defines the transport used for message delivery. With REST or QUIC, every
+	// callback gets its own instance of the transport. Any other transport is used as is
+	// and is closed by Callback.Close.
+	// Default value: REST
 	Transport Transport
 
+	// Signing enables HMAC-SHA256 signing of the requests sent by the built-in REST
+	// transport with global or per-endpoint secrets. Receivers verify requests with
+	// transport.Verify, or transport.VerifyWebhook with the transport.StandardWebhooks
+	// scheme, which signs with the ID of each message. It has no effect on other transports.
+	Signing *Signing
+
+	// TLS configures the TLS connections of the built-in REST transport, such as client
+	// certificates for mutual TLS and a private CA. Certificate files are reloaded when
+	// they change on disk. It has no effect on other transports.
+	TLS *TLS
+
+	// EndPointTLS maps endpoints to their own TLS configuration, overriding TLS.
+	EndPointTLS map[string]*TLS
+
+	// HTTP configures the long-lived HTTP client of the built-in REST transport: timeouts,
+	// connection pool sizes, HTTP/2 and h2c, and proxies. If nil, requests time out after
+	// 30 seconds and use the other defaults of transport.HTTP. It has no effect on other transports.
+	HTTP *HTTP
+
 	// DeliveryMode defines the method for delivering messages to clients.
 	// It can be used to select a notification delivery strategy,
 	// whether it's sending a single message to one client or broadcasting to all clients.
 	DeliveryMode DeliveryMode
 
 	// RetryMode defines the behavior when retrying failed message delivery attempts.
+	// In Broadcast and Quorum delivery modes messages are always repeated on the same endpoint.
+	// Default value: Next
 	RetryMode RetryMode
 
 	// EndPoints specifies the IP addresses or addresses of endpoints for message delivery.
 	// This can be modified in real-time based on server settings, allowing dynamic control over the delivery targets.
 	EndPoints []string
 
+	// Weights assigns weights to endpoints for WeightedRoundRobin delivery mode.
+	// Endpoints without a weight have weight 1; endpoints with weight 0 receive no messages.
+	Weights map[string]int
+
+	// Priorities assigns endpoints to priority tiers for Failover delivery mode.
+	// Endpoints without a tier belong to tier 0, the primary one; higher tiers only
+	// receive messages while all endpoints of the lower tiers are blocked.
+	Priorities map[string]int
+
+	// VirtualNodes is the number of virtual nodes per endpoint on the hash ring in
+	// ConsistentHash delivery mode. More nodes spread keys more evenly.
+	// Default value: 100
+	VirtualNodes int
+
+	// Ordered delivers messages sharing a Message.Key strictly in emission order: the next
+	// message with a key is not sent until the previous one has its final result, that is,
+	// it succeeded, or it failed after its retries and was dead-lettered if a DeadLetter is
+	// configured. Messages with different keys, or without a key, still proceed in parallel.
+	// Combine with ConsistentHash delivery mode to also keep each key on one endpoint.
+	Ordered bool
+
 	// RetryLimit is the maximum number of retry attempts for message delivery.
+	// A failed message is retried according to RetryMode at most RetryLimit times before it is reported as failed.
 	// If the server fails to deliver a message within the set limit, it will temporarily stop sending messages to this endpoint.
 	// Default value: 5
 	RetryLimit int
@@ -75,6 +171,56 @@ type Options struct {
 	// RetryWindow is the period of time during which retries will be counted toward the RetryLimit.
 	// This window ensures that the RetryLimit is not exceeded within a short burst of attempts.
 	RetryWindow time.Duration
+
+	// HalfOpenProbes is the number of probe messages let through at a time to an endpoint
+	// whose circuit breaker became half-open after the block period.
+	// Default value: 1
+	HalfOpenProbes int
+
+	// SuccessThreshold is the number of consecutive successful probes that close the circuit
+	// breaker of a half-open endpoint. A failed probe opens it again.
+	// Default value: 2
+	SuccessThreshold int
+
+	// HealthCheck enables active health checking of endpoints. Endpoints failing their
+	// checks are taken out of rotation until they pass again. If nil, endpoints are only
+	// blocked after messages to them fail. It is ignored unless the Transport is REST
+	// or a *transport.REST, as checks are HTTP requests to the endpoint URLs.
+	HealthCheck *HealthCheck
+
+	// Hedge enables hedged requests: a message whose request has not completed within
+	// the hedge delay is also sent to a second endpoint, the first successful response wins
+	// and the other request is cancelled. Both attempts are reported in Data.Attempts.
+	// Messages are not hedged in Broadcast and Quorum delivery modes, nor in ConsistentHash
+	// mode while the endpoint owning the key is available. If nil, requests are not hedged.
+	Hedge *Hedge
+
+	// CloudEventMode is the mode in which EmitEvent encodes CloudEvents.
+	// Default value: BinaryMode
+	CloudEventMode CloudEventMode
+
+	// Backoff configures exponential backoff with jitter between retry attempts of a message
+	// and for the block period of an endpoint, which then grows from RetryTimeout with every
+	// consecutive block and is never shorter than RetryTimeout. If nil, retries are immediate and the block period is always RetryTimeout.
+	Backoff *Backoff
+
+	// Outbox is an optional persistent log opened with OpenOutbox. When set, Emit returns
+	// only after the message is recorded, and messages without a final result before a restart
+	// are replayed by New. Failed messages are not replayed unless dead-lettering them failed.
+	// Results of replayed messages that complete before On is set are not reported.
+	Outbox *Outbox
+
+	// DeadLetter is an optional sink for messages that exhausted their retries, such as
+	// a FileDeadLetter. Dead-lettered messages can be listed and re-driven through the Callback.
+	DeadLetter DeadLetter
+
+	// Quorum is the number of endpoints that must acknowledge a message in Quorum delivery mode.
+	// Takes precedence over QuorumRatio.
+	Quorum int
+
+	// QuorumRatio is the fraction of endpoints (0..1] that must acknowledge a message
+	// in Quorum delivery mode. If neither Quorum nor QuorumRatio is set, a majority is required.
+	QuorumRatio float64
 }
 
 // defaultOptions initializes default values for Options fields that are not set.
@@ -83,7 +229,7 @@ type Options struct {
 func defaultOptions(opt *Options) *Options {
 
 	// Set default transport to REST if none is specified
-	if opt.Transport == "" {
+	if opt.Transport == nil {
 		opt.Transport = REST
 	}
 
@@ -112,5 +258,40 @@ func defaultOptions(opt *Options) *Options {
 		opt.RetryWindow = time.Second * 3
 	}
 
+	// Set default CloudEvents mode to binary if none is specified
+	if opt.CloudEventMode == "" {
+		opt.CloudEventMode = BinaryMode
+	}
+
+	// Set default number of virtual nodes to 100 if none is specified
+	if opt.VirtualNodes == 0 {
+		opt.VirtualNodes = 100
+	}
+
+	// Set default number of half-open probes to 1 if none is specified
+	if opt.HalfOpenProbes == 0 {
+		opt.HalfOpenProbes = 1
+	}
+
+	// Set default success threshold to 2 if none is specified
+	if opt.SuccessThreshold == 0 {
+		opt.SuccessThreshold = 2
+	}
+
+	// Set default health check values if health checking is enabled
+	if opt.HealthCheck != nil {
+		opt.HealthCheck = defaultHealthCheck(opt.HealthCheck)
+	}
+
+	// Set default hedge values if hedged requests are enabled
+	if opt.Hedge != nil {
+		opt.Hedge = defaultHedge(opt.Hedge)
+	}
+
+	// Set default backoff values if a backoff policy is specified
+	if opt.Backoff != nil {
+		opt.Backoff = defaultBackoff(opt.Backoff)
+	}
+
 	return opt
 }
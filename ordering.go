@@ -0,0 +1,100 @@
+package callback
+
+import (
+	"fmt"
+	"sync"
+)
+
+// sequencer holds back messages so that messages sharing a key are sent one at a time,
+// in emission order. Messages with different keys are not held back by each other.
+type sequencer struct {
+	mu sync.Mutex
+
+	// Messages waiting for the message in flight with the same key. A key is present
+	// while a message with that key is in flight, even if none is waiting.
+	queues map[string][]*message
+}
+
+// newSequencer creates an empty sequencer.
+func newSequencer() *sequencer {
+	return &sequencer{queues: make(map[string][]*message)}
+}
+
+// acquire reports whether the message can be sent now. Otherwise the message is queued
+// behind the message in flight with the same key and is returned by a later release.
+func (s *sequencer) acquire(msg *message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, busy := s.queues[msg.key]
+	if busy {
+		s.queues[msg.key] = append(queue, msg)
+		return false
+	}
+
+	s.queues[msg.key] = nil
+	return true
+}
+
+// release completes the message in flight with the key and returns the next message
+// to send for the key, or nil if none is waiting.
+func (s *sequencer) release(key string) *message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.queues[key]
+	if len(queue) == 0 {
+		delete(s.queues, key)
+		return nil
+	}
+
+	s.queues[key] = queue[1:]
+	return queue[0]
+}
+
+// send emits the message, holding it back in Ordered mode until the previous message
+// with the same key has completed. A message that cannot be emitted lets the next one go.
+func (c *Callback) send(msg *message) error {
+	if c.sequencer == nil || msg.key == "" {
+		return c.emit(msg)
+	}
+
+	if !c.sequencer.acquire(msg) {
+		return nil
+	}
+
+	if err := c.emit(msg); err != nil {
+		go c.advance(msg.key)
+		return err
+	}
+	return nil
+}
+
+// advance sends the next message waiting for the key once the previous one has completed.
+// Messages that cannot be emitted, for example because all endpoints are blocked,
+// are completed as failed and the following message is tried.
+func (c *Callback) advance(key string) {
+	for {
+		msg := c.sequencer.release(key)
+		if msg == nil {
+			return
+		}
+
+		err := c.emit(msg)
+		if err == nil {
+			return
+		}
+
+		data := Data{
+			ID:      msg.id,
+			Header:  msg.header,
+			Success: false,
+			Error: &Error{
+				Code:     0,
+				Message:  fmt.Sprintf("[ERROR] %v", err.Error()),
+				Critical: true,
+			},
+		}
+		c.complete(msg, data)
+	}
+}
@@ -0,0 +1,205 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// orderTransport records the payloads it delivered in order. Payloads starting with
+// "flaky" fail on their first attempt and payloads equal to "hold" wait for release.
+type orderTransport struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	received []string
+	failed   map[string]bool
+}
+
+func (t *orderTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	data := string(msg.Data)
+	if data == "hold" {
+		<-t.release
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(data) >= 5 && data[:5] == "flaky" && !t.failed[data] {
+		t.failed[data] = true
+		return nil, errors.New("unavailable")
+	}
+	t.received = append(t.received, data)
+	return []byte(endpoint), nil
+}
+
+func (t *orderTransport) Close() error {
+	return nil
+}
+
+// TestSequencer tests that messages sharing a key are released one at a time.
+func TestSequencer(t *testing.T) {
+	s := newSequencer()
+	first := &message{key: "k", id: "1"}
+	second := &message{key: "k", id: "2"}
+	other := &message{key: "o", id: "3"}
+
+	if !s.acquire(first) {
+		t.Fatal("expected the first message to be sent now")
+	}
+	if s.acquire(second) {
+		t.Fatal("expected the second message to wait")
+	}
+	if !s.acquire(other) {
+		t.Fatal("expected a message with another key to be sent now")
+	}
+
+	if next := s.release("k"); next != second {
+		t.Fatalf("expected the second message to be released, got %v", next)
+	}
+	if next := s.release("k"); next != nil {
+		t.Fatalf("expected no message to be released, got %v", next)
+	}
+	if !s.acquire(&message{key: "k"}) {
+		t.Error("expected the key to be free again")
+	}
+}
+
+// TestOrdered tests per-key FIFO delivery through the callback.
+func TestOrdered(t *testing.T) {
+	t.Run("key order with retries", func(t *testing.T) {
+		tr := &orderTransport{failed: make(map[string]bool)}
+		clb := New(&Options{
+			Transport: tr,
+			Ordered:   true,
+			RetryMode: Next,
+			EndPoints: []string{"a", "b", "c"},
+		})
+		defer clb.Close()
+
+		results := make(chan *Data, 10)
+		clb.On(func(data *Data) { results <- data })
+
+		// The first message fails once, so without ordering the others would overtake it.
+		payloads := []string{"flaky-1", "2", "flaky-3", "4", "5"}
+		for _, payload := range payloads {
+			if _, err := clb.EmitMessage(context.Background(), &Message{Key: "k", Data: []byte(payload)}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		for range payloads {
+			if data := waitData(t, results); !data.Success {
+				t.Fatalf("expected success, got %v", data.Error)
+			}
+		}
+
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		if fmt.Sprint(tr.received) != fmt.Sprint(payloads) {
+			t.Errorf("expected delivery order %v, got %v", payloads, tr.received)
+		}
+	})
+
+	t.Run("other keys in parallel", func(t *testing.T) {
+		tr := &orderTransport{release: make(chan struct{}), failed: make(map[string]bool)}
+		clb := New(&Options{
+			Transport: tr,
+			Ordered:   true,
+			EndPoints: []string{"a", "b"},
+		})
+		defer clb.Close()
+
+		results := make(chan *Data, 10)
+		clb.On(func(data *Data) { results <- data })
+
+		clb.EmitMessage(context.Background(), &Message{Key: "a", Data: []byte("hold")})
+		clb.EmitMessage(context.Background(), &Message{Key: "a", Data: []byte("after hold")})
+		clb.EmitMessage(context.Background(), &Message{Key: "b", Data: []byte("free")})
+
+		// The message with another key is delivered while key a is held up.
+		if data := waitData(t, results); data.Response == nil || !data.Success {
+			t.Fatalf("expected success, got %v", data.Error)
+		}
+		select {
+		case data := <-results:
+			t.Fatalf("expected key a to wait, got a result for %s", data.ID)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(tr.release)
+		waitData(t, results)
+		waitData(t, results)
+
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		if fmt.Sprint(tr.received) != "[free hold after hold]" {
+			t.Errorf("expected delivery order [free hold after hold], got %v", tr.received)
+		}
+	})
+
+	t.Run("failed message lets the next go", func(t *testing.T) {
+		clb := New(&Options{
+			Transport: &flakyTransport{failures: map[string]int{"a": 2}},
+			Ordered:   true,
+			RetryMode: Repeat,
+			// The first message fails on its single retry.
+			RetryLimit: 1,
+			EndPoints:  []string{"a"},
+		})
+		defer clb.Close()
+
+		results := make(chan *Data, 10)
+		clb.On(func(data *Data) { results <- data })
+
+		first, _ := clb.EmitMessage(context.Background(), &Message{Key: "k", Data: []byte("1")})
+		second, _ := clb.EmitMessage(context.Background(), &Message{Key: "k", Data: []byte("2")})
+
+		if data := waitData(t, results); data.ID != first || data.Success {
+			t.Fatalf("expected the first message to fail, got %+v", data)
+		}
+
+		// The second message is sent, or failed if the endpoint got blocked, instead of waiting forever.
+		if data := waitData(t, results); data.ID != second {
+			t.Fatalf("expected a result for the second message, got %+v", data)
+		}
+	})
+}
+
+// TestOrdered_RemovedEndpoint tests that messages queued on a removed endpoint are failed,
+// so their keys and pending requests do not wait forever.
+func TestOrdered_RemovedEndpoint(t *testing.T) {
+	tr := &orderTransport{release: make(chan struct{}), failed: make(map[string]bool)}
+	defer close(tr.release)
+	clb := New(&Options{
+		Transport: tr,
+		Ordered:   true,
+		EndPoints: []string{"a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) { results <- data })
+
+	// The message with key k waits in the queue of a behind a held message.
+	clb.EmitMessage(context.Background(), &Message{Key: "other", Data: []byte("hold")})
+	for len(clb.workers()[0].messageQueue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queued, _ := clb.EmitMessage(context.Background(), &Message{Key: "k", Data: []byte("queued")})
+
+	clb.SyncEndPoint([]string{"b"})
+	if data := waitData(t, results); data.ID != queued || data.Success {
+		t.Fatalf("expected the queued message to fail, got %+v", data)
+	}
+
+	// The next message with key k is sent to the remaining endpoint.
+	next, _ := clb.EmitMessage(context.Background(), &Message{Key: "k", Data: []byte("next")})
+	if data := waitData(t, results); data.ID != next || !data.Success || data.Point != "b" {
+		t.Fatalf("expected the next message to be delivered to b, got %+v", data)
+	}
+}
@@ -0,0 +1,267 @@
+package callback
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// Outbox is a persistent append-only log of emitted messages on local disk.
+// When set in Options, Emit returns only after the message is durably recorded,
+// messages are marked done once they succeed or finally fail, and messages without a final
+// result before the process stopped are replayed by New, giving at-least-once delivery.
+// A failed message is final once it is dead-lettered, or right away if no DeadLetter is
+// configured, and a message failed by its own context is final as well.
+type Outbox struct {
+
+	// A mutex for synchronizing writes to the log.
+	mu sync.Mutex
+
+	// The path of the log file.
+	path string
+
+	// The log file opened for appending.
+	file *os.File
+
+	// Messages recorded in the log that are not delivered yet, in emission order.
+	pending []outboxRecord
+
+	// IDs of the messages that are recorded and not acknowledged yet.
+	live map[string]struct{}
+
+	// The number of acks appended to the log since it was last compacted.
+	acks int
+
+	// The first error that occurred while acknowledging a message.
+	err error
+}
+
+// outboxRecord is a single line of the outbox log.
+type outboxRecord struct {
+	Op     string            `json:"op"` // "put" records a message, "ack" marks it delivered.
+	ID     string            `json:"id"`
+	Header map[string]string `json:"header,omitempty"`
+	Key    string            `json:"key,omitempty"`
+	Data   []byte            `json:"data,omitempty"`
+}
+
+// OpenOutbox opens the outbox log at path, creating it if needed. Undelivered messages
+// recorded by a previous process are kept for replay, and the log is compacted to them.
+func OpenOutbox(path string) (*Outbox, error) {
+	o := &Outbox{path: path, live: make(map[string]struct{})}
+
+	// Read the undelivered messages left by a previous process.
+	pending, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	o.pending = pending
+	for _, record := range pending {
+		o.live[record.ID] = struct{}{}
+	}
+
+	// Rewrite the log with the undelivered messages only.
+	if err := o.compact(pending); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// load reads the log and returns messages that were recorded but never acknowledged,
+// in emission order. A truncated last line, left by a crash in the middle of a write, is ignored.
+func (o *Outbox) load() ([]outboxRecord, error) {
+	file, err := os.Open(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []outboxRecord
+	live := make(map[string]struct{})
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var record outboxRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+
+		switch record.Op {
+		case "put":
+			records = append(records, record)
+			live[record.ID] = struct{}{}
+		case "ack":
+			delete(live, record.ID)
+		}
+	}
+
+	// Keep the records that are still live, in emission order.
+	var pending []outboxRecord
+	for _, record := range records {
+		if _, ok := live[record.ID]; ok {
+			pending = append(pending, record)
+		}
+	}
+	return pending, nil
+}
+
+// compact atomically replaces the log with the given records and reopens it for appending.
+func (o *Outbox) compact(records []outboxRecord) error {
+	tmp := o.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		if err := writeRecord(writer, record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// Close the log that is being replaced.
+	if o.file != nil {
+		if err := o.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, o.path); err != nil {
+		return err
+	}
+
+	o.acks = 0
+	o.file, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	return err
+}
+
+// put durably records a message before it is dispatched.
+func (o *Outbox) put(msg *message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := writeRecord(o.file, outboxRecord{Op: "put", ID: msg.id, Header: msg.header, Key: msg.key, Data: msg.data}); err != nil {
+		return err
+	}
+	if err := o.file.Sync(); err != nil {
+		return err
+	}
+
+	o.live[msg.id] = struct{}{}
+	return nil
+}
+
+// ack marks a message as delivered. Losing an ack in a crash only causes a redelivery,
+// so it is not synced. The log is truncated once no message is pending, and compacted
+// once it holds more acks than pending messages, so it does not grow without bound.
+// A failed ack is remembered and returned by Err.
+func (o *Outbox) ack(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	err := o.remove(id)
+	if err != nil && o.err == nil {
+		o.err = err
+	}
+	return err
+}
+
+// remove records the ack of a message. It must be called with o.mu held.
+func (o *Outbox) remove(id string) error {
+	if _, ok := o.live[id]; !ok {
+		return nil
+	}
+	delete(o.live, id)
+
+	// Nothing to replay, start the log over.
+	if len(o.live) == 0 {
+		o.acks = 0
+		return o.file.Truncate(0)
+	}
+
+	if err := writeRecord(o.file, outboxRecord{Op: "ack", ID: id}); err != nil {
+		return err
+	}
+	o.acks++
+
+	// Most of the log is delivered messages, rewrite it with the pending ones only.
+	if o.acks > len(o.live) {
+		records, err := o.load()
+		if err != nil {
+			return err
+		}
+
+		var pending []outboxRecord
+		for _, record := range records {
+			if _, ok := o.live[record.ID]; ok {
+				pending = append(pending, record)
+			}
+		}
+		return o.compact(pending)
+	}
+	return nil
+}
+
+// replay returns the undelivered messages found when the outbox was opened, once.
+func (o *Outbox) replay() []outboxRecord {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := o.pending
+	o.pending = nil
+	return pending
+}
+
+// Err returns the first error that occurred while marking a message as delivered, if any.
+// A message whose ack failed may be replayed by the next process.
+func (o *Outbox) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.err
+}
+
+// Close closes the outbox log.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.file.Close()
+}
+
+// writeRecord writes a record as a single JSON line.
+func writeRecord(w io.Writer, record outboxRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
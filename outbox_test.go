@@ -0,0 +1,272 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestOutbox_Reopen tests that only unacknowledged messages are kept across reopening.
+func TestOutbox_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+
+	outbox, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		msg := newMessage(context.Background(), []byte("payload "+id))
+		msg.id = id
+		msg.header = map[string]string{"X-Tenant": "acme"}
+		if err := outbox.put(msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	outbox.ack("2")
+	outbox.Close()
+
+	// Simulate a crash in the middle of writing a record.
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	file.Write([]byte(`{"op":"ack","id":"1"`))
+	file.Close()
+
+	outbox, err = OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer outbox.Close()
+
+	pending := outbox.replay()
+	if len(pending) != 2 || pending[0].ID != "1" || pending[1].ID != "3" {
+		t.Fatalf("expected messages 1 and 3 to be pending, got %+v", pending)
+	}
+	if string(pending[1].Data) != "payload 3" || pending[1].Header["X-Tenant"] != "acme" {
+		t.Errorf("expected the message to be restored, got %+v", pending[1])
+	}
+
+	// Acknowledging every message empties the log.
+	outbox.ack("1")
+	outbox.ack("3")
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("expected an empty log, got %d bytes", info.Size())
+	}
+}
+
+// TestOutbox_Compact tests that the log does not grow while a message stays pending.
+func TestOutbox_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+
+	outbox, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	put := func(id string) {
+		msg := newMessage(context.Background(), []byte("payload "+id))
+		msg.id = id
+		if err := outbox.put(msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// A message that is never acknowledged keeps the log from being truncated.
+	put("stuck")
+	info, _ := os.Stat(path)
+	size := info.Size()
+
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		put(id)
+		if err := outbox.ack(id); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if info, _ := os.Stat(path); info.Size() > 4*size {
+		t.Errorf("expected the log to be compacted, got %d bytes", info.Size())
+	}
+
+	// New records are still appended to the compacted log.
+	put("last")
+	outbox.Close()
+
+	outbox, err = OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer outbox.Close()
+
+	pending := outbox.replay()
+	if len(pending) != 2 || pending[0].ID != "stuck" || pending[1].ID != "last" {
+		t.Errorf("expected messages stuck and last to be pending, got %+v", pending)
+	}
+}
+
+// TestOutbox_Replay tests that a message not delivered before a restart is replayed by New.
+func TestOutbox_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+
+	// The first process stops while the message is in flight.
+	outbox, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	clb := New(&Options{
+		Transport: &orderTransport{release: release, failed: make(map[string]bool)},
+		Outbox:    outbox,
+		EndPoints: []string{"custom://a"},
+	})
+	if _, err := clb.Emit([]byte("hold")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for len(clb.workers()[0].messageQueue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clb.Close()
+	outbox.Close()
+
+	// The second process replays and delivers it.
+	outbox, err = OpenOutbox(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer outbox.Close()
+
+	tr := &testTransport{}
+	clb = New(&Options{
+		Transport: tr,
+		Outbox:    outbox,
+		EndPoints: []string{"custom://a"},
+	})
+	defer clb.Close()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		tr.mu.Lock()
+		sent := len(tr.sent)
+		tr.mu.Unlock()
+
+		if sent == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the replay")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// The delivered message is acknowledged.
+	deadline = time.Now().Add(time.Second * 5)
+	for {
+		outbox.mu.Lock()
+		live := len(outbox.live)
+		outbox.mu.Unlock()
+
+		if live == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the acknowledgement")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// TestOutbox_FinalFailure tests that failures reported as final are not replayed.
+func TestOutbox_FinalFailure(t *testing.T) {
+	// live returns the number of messages the outbox would replay.
+	live := func(outbox *Outbox) int {
+		outbox.mu.Lock()
+		defer outbox.mu.Unlock()
+		return len(outbox.live)
+	}
+
+	t.Run("without dead letter", func(t *testing.T) {
+		outbox, err := OpenOutbox(filepath.Join(t.TempDir(), "outbox.log"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer outbox.Close()
+
+		clb := New(&Options{
+			Transport:  &testTransport{err: errors.New("refused")},
+			RetryLimit: 1,
+			Outbox:     outbox,
+			EndPoints:  []string{"custom://a"},
+		})
+		defer clb.Close()
+
+		if _, err := clb.Request(context.Background(), []byte("payload")); err == nil {
+			t.Fatal("expected delivery to fail")
+		}
+		if n := live(outbox); n != 0 {
+			t.Errorf("expected the failed message to be acknowledged, got %d pending", n)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		outbox, err := OpenOutbox(filepath.Join(t.TempDir(), "outbox.log"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer outbox.Close()
+		sink, err := NewFileDeadLetter(t.TempDir())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		clb := New(&Options{
+			Transport:  &hangTransport{},
+			RetryLimit: 1,
+			Outbox:     outbox,
+			DeadLetter: sink,
+			EndPoints:  []string{"slow"},
+		})
+		defer clb.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		if _, err := clb.Request(ctx, []byte("payload")); err == nil {
+			t.Fatal("expected delivery to fail")
+		}
+
+		// Request returns when ctx is done, the failed attempt is settled right after.
+		deadline := time.Now().Add(time.Second * 5)
+		for live(outbox) != 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the cancelled message to be acknowledged")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		if letters, _ := clb.DeadLetters(); len(letters) != 0 {
+			t.Errorf("expected the cancelled message not to be dead-lettered, got %+v", letters)
+		}
+	})
+}
+
+// TestOutbox_Err tests that a failed ack is reported by Err.
+func TestOutbox_Err(t *testing.T) {
+	outbox, err := OpenOutbox(filepath.Join(t.TempDir(), "outbox.log"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		msg := newMessage(context.Background(), []byte("payload"))
+		msg.id = id
+		outbox.put(msg)
+	}
+	outbox.Close()
+
+	if err := outbox.ack("1"); err == nil {
+		t.Fatal("expected the ack to fail on a closed log")
+	}
+	if outbox.Err() == nil {
+		t.Error("expected Err to report the failed ack")
+	}
+}
@@ -0,0 +1,24 @@
+package callback
+
+import "math"
+
+// quorum sends the message to every available worker and reports the delivery as successful
+// once the quorum of workers acknowledges it. The aggregate Summary is passed to the
+// On handler as soon as the quorum is reached or can no longer be reached.
+func (c *Callback) quorum(msg *message) error {
+	workers := c.workers()
+	return c.fanout(workers, msg, c.quorumSize(len(workers)))
+}
+
+// quorumSize calculates the number of acknowledgements required out of total endpoints.
+// An explicit count takes precedence over a fraction; by default a majority is required.
+func (c *Callback) quorumSize(total int) int {
+	switch {
+	case c.quorumCount > 0:
+		return c.quorumCount
+	case c.quorumRatio > 0:
+		return int(math.Ceil(c.quorumRatio * float64(total)))
+	default:
+		return total/2 + 1
+	}
+}
@@ -0,0 +1,126 @@
+package callback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestQuorumSize tests the calculation of the required number of acknowledgements.
+func TestQuorumSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		callback *Callback
+		total    int
+		expected int
+	}{
+		{name: "Default majority of 3", callback: &Callback{}, total: 3, expected: 2},
+		{name: "Default majority of 4", callback: &Callback{}, total: 4, expected: 3},
+		{name: "Explicit count", callback: &Callback{quorumCount: 1}, total: 5, expected: 1},
+		{name: "Ratio rounds up", callback: &Callback{quorumRatio: 0.5}, total: 5, expected: 3},
+		{name: "Count takes precedence", callback: &Callback{quorumCount: 4, quorumRatio: 0.1}, total: 5, expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.callback.quorumSize(tt.total); got != tt.expected {
+				t.Errorf("quorumSize(%d) = %d, want %d", tt.total, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGroup_Quorum tests that a quorum group decides as soon as the outcome is known.
+func TestGroup_Quorum(t *testing.T) {
+	t.Run("Reached", func(t *testing.T) {
+		g := &group{pending: 3, required: 2}
+		if _, ok := g.add(&Data{Success: true}); ok {
+			t.Fatal("expected group to wait for the quorum")
+		}
+		data, ok := g.add(&Data{Success: true})
+		if !ok || !data.Success {
+			t.Fatalf("expected quorum to be reached, got %v %+v", ok, data)
+		}
+		if data.Summary.Pending != 1 {
+			t.Errorf("expected 1 pending endpoint, got %d", data.Summary.Pending)
+		}
+
+		// Late results must not report the aggregate again.
+		if _, ok := g.add(&Data{Success: true}); ok {
+			t.Error("expected late result to be ignored")
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		g := &group{pending: 3, required: 2}
+		if _, ok := g.add(&Data{Success: false}); ok {
+			t.Fatal("expected group to wait while the quorum is still reachable")
+		}
+		data, ok := g.add(&Data{Success: false})
+		if !ok || data.Success {
+			t.Fatalf("expected quorum to fail, got %v %+v", ok, data)
+		}
+	})
+}
+
+// TestQuorum_NotEnoughEndpoints tests that an error is returned when too many workers are blocked.
+func TestQuorum_NotEnoughEndpoints(t *testing.T) {
+	callback := &Callback{
+		endPoints: []*Worker{
+			{messageQueue: make(chan *message, 1)},
+			{messageQueue: make(chan *message, 1), blockedUntil: time.Now().Add(time.Minute)},
+			{messageQueue: make(chan *message, 1), blockedUntil: time.Now().Add(time.Minute)},
+		},
+	}
+
+	if err := callback.quorum(newMessage(context.Background(), []byte("test data"))); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// TestQuorum_Delivery tests that the aggregate result is successful when a majority acknowledges.
+func TestQuorum_Delivery(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fail.Close()
+
+	clb := New(&Options{
+		DeliveryMode: Quorum,
+		EndPoints:    []string{ok.URL, ok.URL + "/second", fail.URL},
+	})
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte(`{"data": "test"}`)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for {
+		select {
+		case data := <-results:
+			if data.Summary == nil {
+				continue
+			}
+			if !data.Success {
+				t.Errorf("expected quorum delivery to succeed, got %+v", data.Summary)
+			}
+			if data.Summary.Quorum != 2 {
+				t.Errorf("expected quorum of 2, got %d", data.Summary.Quorum)
+			}
+			return
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for quorum result")
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmelum/callback/transport"
+)
+
+// flakyTransport is a Transport whose endpoints fail a configured number of times before succeeding.
+type flakyTransport struct {
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func (t *flakyTransport) Send(ctx context.Context, endpoint string, msg *transport.Message) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failures[endpoint] != 0 {
+		t.failures[endpoint]--
+		return nil, errors.New("unavailable")
+	}
+	return []byte(endpoint), nil
+}
+
+func (t *flakyTransport) Close() error {
+	return nil
+}
+
+// points returns the endpoints of the attempts in order.
+func points(attempts []Attempt) []string {
+	result := make([]string, 0, len(attempts))
+	for _, attempt := range attempts {
+		result = append(result, attempt.Point)
+	}
+	return result
+}
+
+// TestRetry_Next tests that a failed message is retried on the next worker.
+func TestRetry_Next(t *testing.T) {
+	clb := New(&Options{
+		Transport: &flakyTransport{failures: map[string]int{"a": -1}},
+		RetryMode: Next,
+		EndPoints: []string{"a", "b"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if !data.Success || data.Point != "b" {
+		t.Fatalf("expected success on b, got %+v", data)
+	}
+	if got := points(data.Attempts); !slicesEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected attempts [a b], got %v", got)
+	}
+	if data.Attempts[0].Error == nil || data.Attempts[1].Error != nil {
+		t.Errorf("expected only the first attempt to fail, got %+v", data.Attempts)
+	}
+}
+
+// TestRetry_Repeat tests that a failed message is retried on the same worker.
+func TestRetry_Repeat(t *testing.T) {
+	clb := New(&Options{
+		Transport: &flakyTransport{failures: map[string]int{"a": 2}},
+		RetryMode: Repeat,
+		EndPoints: []string{"a", "b"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if !data.Success || data.Point != "a" {
+		t.Fatalf("expected success on a, got %+v", data)
+	}
+	if got := points(data.Attempts); !slicesEqual(got, []string{"a", "a", "a"}) {
+		t.Errorf("expected attempts [a a a], got %v", got)
+	}
+}
+
+// TestRetry_Limit tests that a message is reported as failed after RetryLimit retries.
+func TestRetry_Limit(t *testing.T) {
+	clb := New(&Options{
+		Transport:  &flakyTransport{failures: map[string]int{"a": -1}},
+		RetryMode:  Repeat,
+		RetryLimit: 3,
+		EndPoints:  []string{"a"},
+	})
+	defer clb.Close()
+
+	results := make(chan *Data, 10)
+	clb.On(func(data *Data) {
+		results <- data
+	})
+
+	if _, err := clb.Emit([]byte("payload")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := waitData(t, results)
+	if data.Success {
+		t.Fatalf("expected failure, got %+v", data)
+	}
+	if len(data.Attempts) != 4 {
+		t.Errorf("expected 4 attempts, got %d", len(data.Attempts))
+	}
+}
+
+// TestRetry_Backoff tests that retries delayed by the backoff policy are still delivered.
+func TestRetry_Backoff(t *testing.T) {
+	for _, mode := range []RetryMode{Repeat, Next} {
+		t.Run(string(mode), func(t *testing.T) {
+			clb := New(&Options{
+				Transport: &flakyTransport{failures: map[string]int{"a": 2}},
+				RetryMode: mode,
+				Backoff:   &Backoff{Initial: time.Millisecond * 10, Jitter: NoJitter},
+				EndPoints: []string{"a"},
+			})
+			defer clb.Close()
+
+			results := make(chan *Data, 10)
+			clb.On(func(data *Data) {
+				results <- data
+			})
+
+			start := time.Now()
+			if _, err := clb.Emit([]byte("payload")); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			data := waitData(t, results)
+			if !data.Success || len(data.Attempts) != 3 {
+				t.Fatalf("expected success after 3 attempts, got %+v", data)
+			}
+
+			// Two retries wait 10ms and 20ms.
+			if elapsed := time.Since(start); elapsed < time.Millisecond*30 {
+				t.Errorf("expected retries to be delayed by at least 30ms, got %v", elapsed)
+			}
+		})
+	}
+}
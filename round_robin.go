@@ -2,44 +2,53 @@ package callback
 
 import (
 	"errors"
-	"time"
 )
 
-// roundRobin distributes data to available workers in a round-robin manner.
-// It iterates over all endpoints (workers) in the c.endPoints slice and
-// sends data to the first available worker's message queue. If a worker
-// is blocked, it skips to the next one. If all workers are blocked,
-// it returns an error indicating unavailability.
-func (c *Callback) roundRobin(data []byte) error {
-	// Loop through all endpoints in c.endPoints to find an available worker.
-	for i := 0; i < len(c.endPoints); i++ {
-
-		// Calculate the index of the current worker based on roundRobinIndex.
-		// Increment roundRobinIndex by 1, subtract 1 to match the zero-based
-		// indexing in arrays, then use modulo to cycle through endpoints
-		// continuously in a round-robin manner.
-		index := int(c.roundRobinIndex.Add(1)-1) % len(c.endPoints)
+// ErrAllBlocked is returned when a message cannot be emitted because every endpoint
+// is blocked, unhealthy or removed.
+var ErrAllBlocked = errors.New("all endpoints are blocked due to unavailability")
+
+// roundRobin distributes messages to available workers in a round-robin manner.
+// It sends the message to the queue of the next available worker, waiting for room
+// until the message context is done. If all workers are blocked, it returns an error
+// indicating unavailability.
+func (c *Callback) roundRobin(msg *message) error {
+	// Send the message to the queue of the next available worker. If no worker is
+	// available, all workers are currently blocked and unable to process the data.
+	// Only one worker processes this particular message in each roundRobin call.
+	return c.enqueueNext(msg, c.nextRoundRobin)
+}
 
-		// Retrieve the worker at the calculated index.
-		worker := c.endPoints[index]
+// nextRoundRobin returns the next available worker in round-robin order.
+// It iterates over all endpoints (workers) starting after the last selected one,
+// skipping blocked workers. It returns nil if all workers are blocked.
+func (c *Callback) nextRoundRobin() *Worker {
+	// Take a snapshot of the endpoints so the list can change concurrently.
+	workers := c.workers()
 
-		// Check if this worker is available by comparing the current time with
-		// worker.blockedUntil. If blockedUntil is in the future, the worker is
-		// considered unavailable, so we continue to the next worker.
-		if !time.Now().After(worker.blockedUntil) {
-			continue
-		}
+	// Loop through all endpoints to find an available worker.
+	for i := 0; i < len(workers); i++ {
+
+		// Calculate the index of the current worker based on roundRobinIndex,
+		// cycling through endpoints continuously in a round-robin manner.
+		index := c.roundRobinStart(len(workers))
 
-		// If the worker is available, send the data to the worker's message queue.
-		// worker.messageQueue is assumed to be a channel that the worker uses to receive tasks.
-		worker.messageQueue <- data
+		// Retrieve the worker at the calculated index.
+		worker := workers[index]
 
-		// Exit the function after sending data to one worker, ensuring that only
-		// one worker processes this particular data payload in each roundRobin call.
-		return nil
+		// Check if this worker is available. If blockedUntil is in the future,
+		// the worker is considered unavailable, so we continue to the next worker.
+		if worker.available() {
+			return worker
+		}
 	}
 
-	// If no worker was available, return an error indicating that all workers
-	// are currently blocked and unable to process the data.
-	return errors.New("all endpoints are blocked due to unavailability")
+	return nil
+}
+
+// roundRobinStart advances roundRobinIndex and returns its previous value modulo n.
+// The counter is unsigned and reduced before the conversion to int, so the index
+// never becomes negative when the counter wraps around.
+func (c *Callback) roundRobinStart(n int) int {
+	return int((c.roundRobinIndex.Add(1) - 1) % uint32(n))
 }
@@ -1,6 +1,8 @@
 package callback
 
 import (
+	"context"
+	"math"
 	"testing"
 	"time"
 )
@@ -8,7 +10,7 @@ import (
 // TestRoundRobin_Success tests that data is successfully sent to an available worker.
 func TestRoundRobin_Success(t *testing.T) {
 	// Create a message queue and an available worker (blockedUntil is in the past).
-	messageQueue := make(chan []byte, 1)
+	messageQueue := make(chan *message, 1)
 	worker := &Worker{
 		messageQueue: messageQueue,
 		blockedUntil: time.Now().Add(-time.Minute), // worker is immediately available
@@ -19,7 +21,7 @@ func TestRoundRobin_Success(t *testing.T) {
 
 	// Attempt to send data and check that no error is returned.
 	data := []byte("test data")
-	err := callback.roundRobin(data)
+	err := callback.roundRobin(newMessage(context.Background(), data))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -27,8 +29,8 @@ func TestRoundRobin_Success(t *testing.T) {
 	// Verify that the data was sent to the worker's message queue.
 	select {
 	case result := <-worker.messageQueue:
-		if string(result) != string(data) {
-			t.Errorf("expected data %s, got %s", data, result)
+		if string(result.data) != string(data) {
+			t.Errorf("expected data %s, got %s", data, result.data)
 		}
 	default:
 		t.Error("expected data to be sent to the worker, but queue was empty")
@@ -38,7 +40,7 @@ func TestRoundRobin_Success(t *testing.T) {
 // TestRoundRobin_AllBlocked tests that an error is returned when all workers are blocked.
 func TestRoundRobin_AllBlocked(t *testing.T) {
 	// Create a worker that is blocked (blockedUntil is in the future).
-	messageQueue := make(chan []byte, 1)
+	messageQueue := make(chan *message, 1)
 	worker := &Worker{
 		messageQueue: messageQueue,
 		blockedUntil: time.Now().Add(time.Minute), // worker is blocked
@@ -49,7 +51,7 @@ func TestRoundRobin_AllBlocked(t *testing.T) {
 
 	// Attempt to send data and check for the expected error.
 	data := []byte("test data")
-	err := callback.roundRobin(data)
+	err := callback.roundRobin(newMessage(context.Background(), data))
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -63,8 +65,8 @@ func TestRoundRobin_AllBlocked(t *testing.T) {
 func TestRoundRobin_RoundRobinOrder(t *testing.T) {
 	// Create two workers. The first worker is available immediately,
 	// while the second worker is initially blocked.
-	messageQueue1 := make(chan []byte, 1)
-	messageQueue2 := make(chan []byte, 1)
+	messageQueue1 := make(chan *message, 1)
+	messageQueue2 := make(chan *message, 1)
 
 	worker1 := &Worker{
 		messageQueue: messageQueue1,
@@ -80,14 +82,14 @@ func TestRoundRobin_RoundRobinOrder(t *testing.T) {
 
 	// First call should send data to the first available worker (worker1).
 	data1 := []byte("test data 1")
-	err := callback.roundRobin(data1)
+	err := callback.roundRobin(newMessage(context.Background(), data1))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	select {
 	case result := <-worker1.messageQueue:
-		if string(result) != string(data1) {
-			t.Errorf("expected data %s for worker1, got %s", data1, result)
+		if string(result.data) != string(data1) {
+			t.Errorf("expected data %s for worker1, got %s", data1, result.data)
 		}
 	default:
 		t.Error("expected data to be sent to worker1, but queue was empty")
@@ -98,16 +100,38 @@ func TestRoundRobin_RoundRobinOrder(t *testing.T) {
 	data2 := []byte("test data 2")
 
 	// Second call should now send data to worker2 in a round-robin sequence.
-	err = callback.roundRobin(data2)
+	err = callback.roundRobin(newMessage(context.Background(), data2))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	select {
 	case result := <-worker2.messageQueue:
-		if string(result) != string(data2) {
-			t.Errorf("expected data %s for worker2, got %s", data2, result)
+		if string(result.data) != string(data2) {
+			t.Errorf("expected data %s for worker2, got %s", data2, result.data)
 		}
 	default:
 		t.Error("expected data to be sent to worker2, but queue was empty")
 	}
-}
\ No newline at end of file
+}
+
+// TestRoundRobin_IndexWrap tests that the selectors sharing the round-robin index
+// keep picking workers when the index wraps around.
+func TestRoundRobin_IndexWrap(t *testing.T) {
+	workers := []*Worker{{point: "a"}, {point: "b"}, {point: "c"}}
+	callback := &Callback{endPoints: workers}
+
+	for name, next := range map[string]func() *Worker{
+		"round robin":       callback.nextRoundRobin,
+		"least outstanding": callback.nextLeastOutstanding,
+		"failover":          callback.nextFailover,
+	} {
+		t.Run(name, func(t *testing.T) {
+			callback.roundRobinIndex.Store(math.MaxUint32 - 1)
+			for i := 0; i < 4; i++ {
+				if worker := next(); worker == nil {
+					t.Fatal("expected a worker")
+				}
+			}
+		})
+	}
+}
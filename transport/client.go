@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTP configures the long-lived HTTP client of the REST transport. Connections to
+// endpoints are kept alive and reused by all requests, including those of all workers.
+type HTTP struct {
+
+	// Timeout bounds a whole request, from dialing to reading the response body.
+	// A negative value disables it, leaving requests bounded by their context only.
+	// Default value: time.Second * 30
+	Timeout time.Duration
+
+	// DialTimeout bounds establishing a TCP connection.
+	// Default value: time.Second * 10
+	DialTimeout time.Duration
+
+	// TLSHandshakeTimeout bounds the TLS handshake.
+	// Default value: time.Second * 10
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout bounds waiting for the response headers once the request is sent.
+	// Zero leaves it bounded by Timeout only.
+	ResponseHeaderTimeout time.Duration
+
+	// KeepAlive is the interval of TCP keep-alive probes.
+	// Default value: time.Second * 30
+	KeepAlive time.Duration
+
+	// IdleConnTimeout is how long an idle connection is kept in the pool.
+	// Default value: time.Second * 90
+	IdleConnTimeout time.Duration
+
+	// MaxIdleConns limits the idle connections kept across all endpoints.
+	// Default value: 100
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits the idle connections kept per endpoint.
+	// Default value: 10
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the connections per endpoint, including active ones.
+	// Zero means no limit.
+	MaxConnsPerHost int
+
+	// DisableHTTP2 restricts requests to HTTP/1.1. Otherwise HTTP/2 is used with
+	// endpoints that support it over TLS.
+	DisableHTTP2 bool
+
+	// H2C sends requests to "http://" endpoints over unencrypted HTTP/2 with prior
+	// knowledge, for receivers that serve h2c. Such endpoints must support HTTP/2.
+	H2C bool
+
+	// Proxy returns the proxy to use for a request, nil for none.
+	// Default value: http.ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// defaultHTTP initializes default values for HTTP fields that are not set.
+func defaultHTTP(h *HTTP) *HTTP {
+	// Work on a copy so the caller's options are left as they are
+	c := HTTP{}
+	if h != nil {
+		c = *h
+	}
+
+	// Set default total timeout to 30 seconds if none is specified
+	if c.Timeout == 0 {
+		c.Timeout = time.Second * 30
+	}
+
+	// Set default dial timeout to 10 seconds if none is specified
+	if c.DialTimeout == 0 {
+		c.DialTimeout = time.Second * 10
+	}
+
+	// Set default TLS handshake timeout to 10 seconds if none is specified
+	if c.TLSHandshakeTimeout == 0 {
+		c.TLSHandshakeTimeout = time.Second * 10
+	}
+
+	// Set default keep-alive interval to 30 seconds if none is specified
+	if c.KeepAlive == 0 {
+		c.KeepAlive = time.Second * 30
+	}
+
+	// Set default idle connection timeout to 90 seconds if none is specified
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = time.Second * 90
+	}
+
+	// Set default pool size to 100 idle connections if none is specified
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = 100
+	}
+
+	// Set default pool size per endpoint to 10 idle connections if none is specified
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = 10
+	}
+
+	// Use the proxy configured by the environment if none is specified
+	if c.Proxy == nil {
+		c.Proxy = http.ProxyFromEnvironment
+	}
+
+	return &c
+}
+
+// newClient builds an HTTP client from the HTTP options with the given TLS configuration, nil for the defaults.
+func (h *HTTP) newClient(tlsConfig *tls.Config) *http.Client {
+	h = defaultHTTP(h)
+
+	dialer := &net.Dialer{
+		Timeout:   h.DialTimeout,
+		KeepAlive: h.KeepAlive,
+	}
+
+	// Select the protocols: HTTP/1.1 and HTTP/2 over TLS by default, HTTP/2 only with h2c.
+	var protocols http.Protocols
+	switch {
+	case h.H2C:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	case h.DisableHTTP2:
+		protocols.SetHTTP1(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+
+	transport := &http.Transport{
+		Proxy:                 h.Proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   h.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: h.ResponseHeaderTimeout,
+		IdleConnTimeout:       h.IdleConnTimeout,
+		MaxIdleConns:          h.MaxIdleConns,
+		MaxIdleConnsPerHost:   h.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       h.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+		Protocols:             &protocols,
+	}
+
+	client := &http.Client{Transport: transport}
+	if h.Timeout > 0 {
+		client.Timeout = h.Timeout
+	}
+	return client
+}
+
+// client returns the shared HTTP client used for endpoints without a TLS configuration,
+// building it on first use.
+func (r *REST) client() *http.Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shared == nil {
+		r.shared = r.HTTP.newClient(nil)
+	}
+	return r.shared
+}
@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDefaultHTTP tests the default values of the HTTP options.
+func TestDefaultHTTP(t *testing.T) {
+	h := defaultHTTP(nil)
+	if h.Timeout != 30*time.Second || h.DialTimeout != 10*time.Second || h.MaxIdleConnsPerHost != 10 || h.Proxy == nil {
+		t.Errorf("unexpected defaults %+v", h)
+	}
+
+	// The options of the caller are left as they are.
+	options := &HTTP{Timeout: time.Second}
+	if h := defaultHTTP(options); h.Timeout != time.Second || options.DialTimeout != 0 {
+		t.Errorf("expected the options to be copied, got %+v", options)
+	}
+}
+
+// TestHTTP tests the shared, tunable HTTP client of the REST transport.
+func TestHTTP(t *testing.T) {
+	msg := &Message{ID: "1", Data: []byte(`{"data": "test"}`)}
+
+	t.Run("connection reuse", func(t *testing.T) {
+		var conns atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		server.Start()
+		defer server.Close()
+
+		rest := NewREST()
+		defer rest.Close()
+		for i := 0; i < 5; i++ {
+			if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if n := conns.Load(); n != 1 {
+			t.Errorf("expected 1 connection, got %d", n)
+		}
+	})
+
+	t.Run("timeouts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		for name, options := range map[string]*HTTP{
+			"total":           {Timeout: 50 * time.Millisecond},
+			"response header": {ResponseHeaderTimeout: 50 * time.Millisecond},
+		} {
+			rest := &REST{HTTP: options}
+			start := time.Now()
+			if _, err := rest.Send(context.Background(), server.URL, msg); err == nil {
+				t.Errorf("expected the %s timeout to fail the request", name)
+			}
+			if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+				t.Errorf("expected the %s timeout to end the request early, took %v", name, elapsed)
+			}
+		}
+	})
+
+	t.Run("HTTP/2", func(t *testing.T) {
+		var proto atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proto.Store(int32(r.ProtoMajor))
+			w.Write([]byte("ok"))
+		}))
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+		caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+		for major, options := range map[int32]*HTTP{2: nil, 1: {DisableHTTP2: true}} {
+			rest := &REST{TLS: &TLS{CAFile: caFile}, HTTP: options}
+			if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if proto.Load() != major {
+				t.Errorf("expected HTTP/%d, got HTTP/%d", major, proto.Load())
+			}
+		}
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		var proto atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proto.Store(int32(r.ProtoMajor))
+			w.Write([]byte("ok"))
+		}))
+		server.Config.Protocols = new(http.Protocols)
+		server.Config.Protocols.SetHTTP1(true)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+		server.Start()
+		defer server.Close()
+
+		rest := &REST{HTTP: &HTTP{H2C: true}}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if proto.Load() != 2 {
+			t.Errorf("expected HTTP/2, got HTTP/%d", proto.Load())
+		}
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		// The proxy answers itself instead of forwarding the request.
+		var target atomic.Value
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			target.Store(r.URL.String())
+			w.Write([]byte("proxied"))
+		}))
+		defer proxy.Close()
+		proxyURL, _ := url.Parse(proxy.URL)
+
+		rest := &REST{HTTP: &HTTP{Proxy: http.ProxyURL(proxyURL)}}
+		res, err := rest.Send(context.Background(), "http://receiver.invalid/callback", msg)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if string(res) != "proxied" || target.Load() != "http://receiver.invalid/callback" {
+			t.Errorf("expected the request to go through the proxy, got %s for %v", res, target.Load())
+		}
+	})
+}
+
+// TestHTTP_TLSClient tests that TLS configurations share the HTTP options.
+func TestHTTP_TLSClient(t *testing.T) {
+	rest := &REST{HTTP: &HTTP{Timeout: time.Second, MaxConnsPerHost: 3}}
+	client := rest.HTTP.newClient(&tls.Config{ServerName: "example.com"})
+
+	transport := client.Transport.(*http.Transport)
+	if client.Timeout != time.Second || transport.MaxConnsPerHost != 3 || transport.TLSClientConfig.ServerName != "example.com" {
+		t.Errorf("expected the HTTP and TLS options to be applied, got %+v", transport)
+	}
+}
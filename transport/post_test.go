@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -68,4 +69,56 @@ func TestPost(t *testing.T) {
 			t.Fatal("Expected error due to request creation failure, got nil")
 		}
 	})
+
+	// Subtest for a request sent through the REST transport with a cancelled context
+	t.Run("ContextCancelled", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Call Send with the cancelled context
+		_, err := NewREST().Send(ctx, testServer.URL, &Message{Data: []byte(`{"data": "test"}`)})
+		if err == nil {
+			t.Fatal("Expected error due to cancelled context, got nil")
+		}
+	})
+
+	// Subtest for the message ID header
+	t.Run("MessageID", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get(HeaderMessageID)))
+		}))
+		defer testServer.Close()
+
+		resp, err := NewREST().Send(context.Background(), testServer.URL, &Message{ID: "42", Data: []byte(`{}`)})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(resp) != "42" {
+			t.Errorf("Expected message ID 42, got %s", resp)
+		}
+	})
+
+	// Subtest for forwarding the message header as HTTP headers
+	t.Run("Header", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("X-Tenant") + " " + r.Header.Get("Content-Type")))
+		}))
+		defer testServer.Close()
+
+		resp, err := NewREST().Send(context.Background(), testServer.URL, &Message{
+			Header: map[string]string{"X-Tenant": "acme", "Content-Type": "text/plain"},
+			Data:   []byte("payload"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(resp) != "acme text/plain" {
+			t.Errorf("Expected forwarded headers, got %s", resp)
+		}
+	})
 }
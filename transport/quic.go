@@ -0,0 +1,271 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+)
+
+// QUICProtocol is the ALPN protocol negotiated by QUIC connections.
+// Receivers must announce it in their TLS configuration.
+const QUICProtocol = "callback"
+
+// MaxQUICMetadata caps the size of the metadata frame accepted by ReadQUICMessage,
+// so that a peer cannot make the receiver allocate up to 4 GiB with a forged length.
+const MaxQUICMetadata = 64 << 10
+
+var (
+	// ErrQUICMetadataTooLarge is returned when the metadata frame is larger than MaxQUICMetadata.
+	ErrQUICMetadataTooLarge = errors.New("quic message metadata too large")
+
+	// ErrQUICPayloadTooLarge is returned when the payload is larger than the limit
+	// passed to ReadQUICMessageLimit.
+	ErrQUICPayloadTooLarge = errors.New("quic message payload too large")
+)
+
+// QUIC sends payloads over QUIC streams. One connection is kept per endpoint
+// and every message is sent on its own bidirectional stream: the message is written
+// and the send side closed, then the response is read until the receiver closes the stream.
+// A receiver signals a failed delivery by resetting the stream.
+//
+// A message is written as a 4-byte big-endian length, followed by that many bytes of
+// JSON metadata holding the message ID and Header, followed by the payload.
+// Receivers can parse it with ReadQUICMessage.
+type QUIC struct {
+	// TLSConfig is the TLS configuration used to dial endpoints.
+	// QUICProtocol is added to NextProtos when no protocol is set.
+	TLSConfig *tls.Config
+
+	// Config is the QUIC configuration used to dial endpoints, nil for defaults.
+	Config *quic.Config
+
+	// A mutex for synchronizing access to the connection pool.
+	mu sync.Mutex
+
+	// Open connections keyed by endpoint address.
+	conns map[string]*quic.Conn
+}
+
+// NewQUIC creates a QUIC transport with the given TLS configuration.
+func NewQUIC(tlsConfig *tls.Config) *QUIC {
+	return &QUIC{TLSConfig: tlsConfig}
+}
+
+// Send writes the message to a new stream on the connection to host and returns the response.
+// host may be a plain "host:port" address or a "quic://host:port" URL.
+// If the pooled connection is no longer usable, it is redialed once.
+func (q *QUIC) Send(ctx context.Context, host string, msg *Message) ([]byte, error) {
+	addr, err := quicAddr(host)
+	if err != nil {
+		// Return an error if the endpoint cannot be parsed
+		return nil, err
+	}
+
+	conn, err := q.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open a stream on the pooled connection, redialing if the connection was closed
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		q.drop(addr, conn)
+		if conn, err = q.conn(ctx, addr); err != nil {
+			return nil, err
+		}
+		if stream, err = conn.OpenStreamSync(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Abort the stream if the context is cancelled while waiting for the response
+	stop := context.AfterFunc(ctx, func() {
+		stream.CancelWrite(0)
+		stream.CancelRead(0)
+	})
+	defer stop()
+
+	// Write the message and close the send side to mark the end of the request
+	if err := writeQUICMessage(stream, msg); err != nil {
+		return nil, err
+	}
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+
+	// Read the response until the receiver closes the stream
+	responseBody, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseBody, nil
+}
+
+// Close closes all pooled connections. The transport remains usable
+// and dials new connections on the next Send.
+func (q *QUIC) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for addr, conn := range q.conns {
+		conn.CloseWithError(0, "")
+		delete(q.conns, addr)
+	}
+	return nil
+}
+
+// conn returns the pooled connection to addr, dialing a new one if needed.
+func (q *QUIC) conn(ctx context.Context, addr string) (*quic.Conn, error) {
+	q.mu.Lock()
+	conn, ok := q.conns[addr]
+	q.mu.Unlock()
+
+	// Reuse the connection while it is still open
+	if ok && conn.Context().Err() == nil {
+		return conn, nil
+	}
+
+	conn, err := quic.DialAddr(ctx, addr, q.tlsConfig(addr), q.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Another goroutine may have dialed the same endpoint concurrently
+	if existing, ok := q.conns[addr]; ok && existing.Context().Err() == nil {
+		conn.CloseWithError(0, "")
+		return existing, nil
+	}
+
+	if q.conns == nil {
+		q.conns = make(map[string]*quic.Conn)
+	}
+	q.conns[addr] = conn
+	return conn, nil
+}
+
+// drop removes conn from the pool if it is still the pooled connection to addr.
+func (q *QUIC) drop(addr string, conn *quic.Conn) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conns[addr] == conn {
+		delete(q.conns, addr)
+	}
+	conn.CloseWithError(0, "")
+}
+
+// tlsConfig returns the TLS configuration for dialing addr.
+func (q *QUIC) tlsConfig(addr string) *tls.Config {
+	var config *tls.Config
+	if q.TLSConfig != nil {
+		config = q.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+
+	// Negotiate the callback protocol unless the caller chose another one
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{QUICProtocol}
+	}
+
+	// Verify the certificate against the endpoint host name by default
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	return config
+}
+
+// writeQUICMessage writes the metadata frame and the payload of msg to w.
+func writeQUICMessage(w io.Writer, msg *Message) error {
+	metadata, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4, 4+len(metadata)+len(msg.Data))
+	binary.BigEndian.PutUint32(frame, uint32(len(metadata)))
+	frame = append(frame, metadata...)
+	frame = append(frame, msg.Data...)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadQUICMessage reads a message written by the QUIC transport from r, typically
+// a stream accepted by the receiver, until the sender closes its side of the stream.
+// The payload is not limited; use ReadQUICMessageLimit for streams from untrusted peers.
+func ReadQUICMessage(r io.Reader) (*Message, error) {
+	return ReadQUICMessageLimit(r, -1)
+}
+
+// ReadQUICMessageLimit is like ReadQUICMessage, but returns ErrQUICPayloadTooLarge
+// when the payload is larger than limit bytes. A negative limit disables the check.
+func ReadQUICMessageLimit(r io.Reader, limit int64) (*Message, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	// Refuse oversized metadata before allocating it
+	length := binary.BigEndian.Uint32(size[:])
+	if length > MaxQUICMetadata {
+		return nil, ErrQUICMetadataTooLarge
+	}
+
+	// Decode the metadata frame
+	metadata := make([]byte, length)
+	if _, err := io.ReadFull(r, metadata); err != nil {
+		return nil, err
+	}
+	msg := &Message{}
+	if err := json.Unmarshal(metadata, msg); err != nil {
+		return nil, err
+	}
+
+	// The rest of the stream is the payload, read one byte past the limit to detect overflow
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 && int64(len(data)) > limit {
+		return nil, ErrQUICPayloadTooLarge
+	}
+	msg.Data = data
+
+	return msg, nil
+}
+
+// quicAddr converts an endpoint to a "host:port" address.
+func quicAddr(host string) (string, error) {
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		// Not a URL, expect a plain address
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			return "", err
+		}
+		return host, nil
+	}
+
+	if u.Scheme != "quic" {
+		return "", errors.New("unsupported scheme " + u.Scheme)
+	}
+	return u.Host, nil
+}
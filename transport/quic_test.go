@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// newTestCertificate generates a self-signed certificate for 127.0.0.1.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// newTestQUICServer starts an in-process QUIC listener that answers every message with handler.
+// A nil response resets the stream to signal a failed delivery.
+// It returns the listener address and a counter of accepted connections.
+func newTestQUICServer(t *testing.T, handler func(*Message) []byte) (string, *x509.CertPool, *atomic.Int32) {
+	t.Helper()
+
+	cert, pool := newTestCertificate(t)
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{QUICProtocol},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	conns := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				for {
+					stream, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						request, err := ReadQUICMessage(stream)
+						if err != nil {
+							return
+						}
+						response := handler(request)
+						if response == nil {
+							stream.CancelWrite(1)
+							return
+						}
+						stream.Write(response)
+						stream.Close()
+					}()
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), pool, conns
+}
+
+// TestQUIC includes subtests to cover various scenarios of the QUIC transport
+func TestQUIC(t *testing.T) {
+	// Subtest for a successful request and connection reuse
+	t.Run("SuccessAndReuse", func(t *testing.T) {
+		addr, pool, conns := newTestQUICServer(t, func(request *Message) []byte {
+			return append([]byte("ok:"), request.Data...)
+		})
+
+		client := NewQUIC(&tls.Config{RootCAs: pool})
+		defer client.Close()
+
+		for _, host := range []string{addr, "quic://" + addr} {
+			resp, err := client.Send(context.Background(), host, &Message{Data: []byte(`{"data": "test"}`)})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			expected := `ok:{"data": "test"}`
+			if string(resp) != expected {
+				t.Errorf("Expected %s, got %s", expected, resp)
+			}
+		}
+
+		// Both requests must share a single connection
+		if n := conns.Load(); n != 1 {
+			t.Errorf("Expected 1 connection, got %d", n)
+		}
+	})
+
+	// Subtest for a receiver that resets the stream
+	t.Run("StreamReset", func(t *testing.T) {
+		addr, pool, _ := newTestQUICServer(t, func(request *Message) []byte {
+			return nil
+		})
+
+		client := NewQUIC(&tls.Config{RootCAs: pool})
+		defer client.Close()
+
+		_, err := client.Send(context.Background(), addr, &Message{Data: []byte(`{"data": "test"}`)})
+		if err == nil {
+			t.Fatal("Expected error for reset stream, got nil")
+		}
+	})
+
+	// Subtest for reconnecting after the pooled connection was closed
+	t.Run("Redial", func(t *testing.T) {
+		addr, pool, conns := newTestQUICServer(t, func(request *Message) []byte {
+			return request.Data
+		})
+
+		client := NewQUIC(&tls.Config{RootCAs: pool})
+		defer client.Close()
+
+		if _, err := client.Send(context.Background(), addr, &Message{Data: []byte("1")}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		client.Close()
+		if _, err := client.Send(context.Background(), addr, &Message{Data: []byte("2")}); err != nil {
+			t.Fatalf("Expected no error after redial, got %v", err)
+		}
+		if n := conns.Load(); n != 2 {
+			t.Errorf("Expected 2 connections, got %d", n)
+		}
+	})
+
+	// Subtest for the message ID and header metadata
+	t.Run("Metadata", func(t *testing.T) {
+		addr, pool, _ := newTestQUICServer(t, func(request *Message) []byte {
+			return []byte(request.ID + " " + request.Header["X-Tenant"] + " " + string(request.Data))
+		})
+
+		client := NewQUIC(&tls.Config{RootCAs: pool})
+		defer client.Close()
+
+		resp, err := client.Send(context.Background(), addr, &Message{
+			ID:     "42",
+			Header: map[string]string{"X-Tenant": "acme"},
+			Data:   []byte("payload"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(resp) != "42 acme payload" {
+			t.Errorf("Expected metadata to be received, got %s", resp)
+		}
+	})
+
+	// Subtest for an invalid endpoint
+	t.Run("InvalidEndpoint", func(t *testing.T) {
+		client := NewQUIC(nil)
+		if _, err := client.Send(context.Background(), "http://127.0.0.1:1", &Message{}); err == nil {
+			t.Fatal("Expected error for unsupported scheme, got nil")
+		}
+		if _, err := client.Send(context.Background(), "localhost", &Message{}); err == nil {
+			t.Fatal("Expected error for missing port, got nil")
+		}
+	})
+}
+
+// TestReadQUICMessage tests the limits applied when reading a message from a peer
+func TestReadQUICMessage(t *testing.T) {
+	// Subtest for a metadata length above MaxQUICMetadata
+	t.Run("MetadataTooLarge", func(t *testing.T) {
+		frame := []byte{0xff, 0xff, 0xff, 0xff}
+		if _, err := ReadQUICMessage(bytes.NewReader(frame)); !errors.Is(err, ErrQUICMetadataTooLarge) {
+			t.Fatalf("Expected ErrQUICMetadataTooLarge, got %v", err)
+		}
+	})
+
+	// Subtest for a payload above and within the limit
+	t.Run("PayloadLimit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeQUICMessage(&buf, &Message{ID: "1", Data: []byte("payload")}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ReadQUICMessageLimit(bytes.NewReader(buf.Bytes()), 6); !errors.Is(err, ErrQUICPayloadTooLarge) {
+			t.Fatalf("Expected ErrQUICPayloadTooLarge, got %v", err)
+		}
+
+		msg, err := ReadQUICMessageLimit(bytes.NewReader(buf.Bytes()), 7)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if msg.ID != "1" || string(msg.Data) != "payload" {
+			t.Errorf("Expected the message to be read, got %+v", msg)
+		}
+	})
+}
@@ -2,18 +2,90 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"sync"
 )
 
+// REST sends payloads as HTTP POST requests with a JSON body.
+// A delivery is successful when the endpoint answers with 200 OK.
+type REST struct {
+	// Signing configures HMAC signing of requests, nil for unsigned requests.
+	Signing *Signing
+
+	// TLS configures the TLS connections to endpoints, nil for the defaults.
+	TLS *TLS
+
+	// EndPointTLS maps endpoints to their own TLS configuration, overriding TLS.
+	EndPointTLS map[string]*TLS
+
+	// HTTP configures the HTTP client, such as timeouts, pool sizes, HTTP/2 and proxies.
+	// It must not be changed after the first request. Nil uses the defaults of HTTP.
+	HTTP *HTTP
+
+	// A mutex for synchronizing access to the clients.
+	mu sync.Mutex
+
+	// The HTTP client shared by requests to endpoints without a TLS configuration.
+	shared *http.Client
+
+	// HTTP clients keyed by the TLS configuration they were built from.
+	clients map[*TLS]*tlsClient
+}
+
+// NewREST creates a REST transport.
+func NewREST() *REST {
+	return &REST{}
+}
+
+// Send sends msg to host as a POST request bound to ctx and returns the response body.
+// The message ID is sent in the HeaderMessageID header and the message Header as HTTP headers.
+// If Signing is set, the request is signed as well.
+func (r *REST) Send(ctx context.Context, host string, msg *Message) ([]byte, error) {
+	return r.post(ctx, host, msg)
+}
+
+// Client returns the HTTP client used for requests to host, built from its TLS configuration
+// and HTTP, so that other requests to the endpoint, such as health checks, connect the same way.
+func (r *REST) Client(host string) (*http.Client, error) {
+	if t := r.tlsFor(host); t != nil {
+		return r.tlsClient(t)
+	}
+	return r.client(), nil
+}
+
+// Close closes the idle connections of the HTTP clients. The transport stays usable.
+func (r *REST) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shared != nil {
+		r.shared.CloseIdleConnections()
+	}
+
+	for _, c := range r.clients {
+		c.client.CloseIdleConnections()
+	}
+	return nil
+}
+
 // post sends a POST request to the specified host with a JSON body and returns the response body.
 // host: URL of the host to send the request to
 // data: Byte slice representing the JSON body of the request
 // Returns the response body as a byte slice if the request is successful, otherwise an error.
 func Post(host string, data []byte) ([]byte, error) {
+	return defaultREST.post(context.Background(), host, &Message{Data: data})
+}
+
+// defaultREST is the REST transport used by Post, so that its connections are reused.
+var defaultREST = NewREST()
+
+// post sends msg as a POST request bound to ctx. See Post.
+func (r *REST) post(ctx context.Context, host string, msg *Message) ([]byte, error) {
 	// Create a new POST request with the provided host URL and request body
-	req, err := http.NewRequest("POST", host, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", host, bytes.NewBuffer(msg.Data))
 	if err != nil {
 		// Return an error if request creation fails
 		return nil, err
@@ -22,8 +94,29 @@ func Post(host string, data []byte) ([]byte, error) {
 	// Set the content type to JSON, indicating the format of the request body
 	req.Header.Set("Content-Type", "application/json")
 
-	// Initialize a new HTTP client to send the request
-	client := &http.Client{}
+	// Forward the message metadata as HTTP headers, which may override the content type
+	for key, value := range msg.Header {
+		req.Header.Set(key, value)
+	}
+
+	// Pass the message ID so the receiver can correlate and deduplicate deliveries
+	if msg.ID != "" {
+		req.Header.Set(HeaderMessageID, msg.ID)
+	}
+
+	// Sign the body so the receiver can verify the request came from us
+	if r.Signing != nil {
+		if err := r.Signing.sign(req.Header, host, msg.ID, msg.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Use the client for the TLS configuration of the host, or the shared one
+	client, err := r.Client(host)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
 		// Return an error if the request fails
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderTimestamp is the header carrying the Unix time in seconds at which a request was signed.
+	HeaderTimestamp = "X-Callback-Timestamp"
+
+	// HeaderSignature is the header carrying the signatures of a request as a comma-separated
+	// list of "v1=<hex>" entries, one per active secret.
+	HeaderSignature = "X-Callback-Signature"
+
+	// DefaultTolerance is the recommended maximum age of a signed request accepted by Verify.
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrMissingSignature is returned by Verify when a request carries no signature or timestamp.
+	ErrMissingSignature = errors.New("missing signature")
+
+	// ErrInvalidSignature is returned by Verify when no signature matches any of the secrets.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrTimestampExpired is returned by Verify when the timestamp is outside the tolerance.
+	ErrTimestampExpired = errors.New("timestamp outside of tolerance")
+)
+
+// Signing configures HMAC-SHA256 signing of REST requests. Every signed request carries
+// its timestamp in HeaderTimestamp and a signature of "<timestamp>.<body>" for each active
+// secret in HeaderSignature. To rotate a secret, add the new one, update the receivers,
+// then remove the old one; receivers accept a request if any signature matches.
+type Signing struct {
+
+	// Scheme is the format of the signature headers. With StandardWebhooks, requests
+	// follow the Standard Webhooks specification instead, see StandardWebhooks.
+	// Default value: CallbackScheme
+	Scheme Scheme
+
+	// Secrets are the active secrets used for endpoints without their own secrets.
+	Secrets []string
+
+	// EndPoints maps endpoints to their own active secrets, overriding Secrets.
+	EndPoints map[string][]string
+}
+
+// secrets returns the active secrets for host, nil if requests to it are not signed.
+func (s *Signing) secrets(host string) []string {
+	if secrets, ok := s.EndPoints[host]; ok {
+		return secrets
+	}
+	return s.Secrets
+}
+
+// sign sets the signature headers of a request to host with the given message ID and body.
+func (s *Signing) sign(header http.Header, host, id string, body []byte) error {
+	secrets := s.secrets(host)
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	if s.Scheme == StandardWebhooks {
+		return signWebhook(header, secrets, id, body)
+	}
+
+	timestamp := time.Now().Unix()
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, "v1="+Sign(secret, timestamp, body))
+	}
+
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderSignature, strings.Join(signatures, ","))
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" with secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received request with the given headers and body.
+// It succeeds if any signature of the request matches any of the secrets, so both sides
+// can rotate secrets independently, and the timestamp is within tolerance of the current
+// time, which bounds replays. Use DefaultTolerance unless there is a reason not to.
+func Verify(header http.Header, body []byte, tolerance time.Duration, secrets ...string) error {
+	value, signatures := header.Get(HeaderTimestamp), header.Get(HeaderSignature)
+	if value == "" || signatures == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestampExpired
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(Sign(secret, timestamp, body))
+		for _, signature := range strings.Split(signatures, ",") {
+			version, value, ok := strings.Cut(strings.TrimSpace(signature), "=")
+			if ok && version == "v1" && hmac.Equal([]byte(value), expected) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
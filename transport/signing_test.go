@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedServer starts a server that verifies requests with the given secrets.
+func signedServer(t *testing.T, secrets ...string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(r.Header, body, DefaultTolerance, secrets...); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestSigning tests signing requests and verifying them on the receiving side.
+func TestSigning(t *testing.T) {
+	msg := &Message{ID: "1", Data: []byte(`{"data": "test"}`)}
+
+	t.Run("global secret", func(t *testing.T) {
+		server := signedServer(t, "secret")
+		rest := &REST{Signing: &Signing{Secrets: []string{"secret"}}}
+
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		server := signedServer(t, "secret")
+
+		if _, err := NewREST().Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected the unsigned request to be rejected")
+		}
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		// The sender signs with the old and the new secret while receivers are updated.
+		rest := &REST{Signing: &Signing{Secrets: []string{"old", "new"}}}
+
+		for _, secret := range []string{"old", "new"} {
+			server := signedServer(t, secret)
+			if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+				t.Errorf("expected a receiver with the %s secret to accept, got %v", secret, err)
+			}
+		}
+	})
+
+	t.Run("per endpoint", func(t *testing.T) {
+		server := signedServer(t, "own")
+		other := signedServer(t, "global")
+		rest := &REST{Signing: &Signing{
+			Secrets:   []string{"global"},
+			EndPoints: map[string][]string{server.URL: {"own"}},
+		}}
+
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Errorf("expected the endpoint secret to be used, got %v", err)
+		}
+		if _, err := rest.Send(context.Background(), other.URL, msg); err != nil {
+			t.Errorf("expected the global secret to be used, got %v", err)
+		}
+	})
+}
+
+// TestVerify tests the rejection of invalid signatures.
+func TestVerify(t *testing.T) {
+	body := []byte("payload")
+	signed := func(timestamp int64, signature string) http.Header {
+		header := http.Header{}
+		header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		header.Set(HeaderSignature, signature)
+		return header
+	}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		err    error
+	}{
+		{"valid", signed(now, "v1="+Sign("secret", now, body)), body, nil},
+		{"one of many", signed(now, "v1=00, v1="+Sign("secret", now, body)), body, nil},
+		{"missing", http.Header{}, body, ErrMissingSignature},
+		{"wrong secret", signed(now, "v1="+Sign("other", now, body)), body, ErrInvalidSignature},
+		{"tampered body", signed(now, "v1="+Sign("secret", now, body)), []byte("tampered"), ErrInvalidSignature},
+		{"unknown version", signed(now, "v0="+Sign("secret", now, body)), body, ErrInvalidSignature},
+		{"expired", signed(now-3600, "v1="+Sign("secret", now-3600, body)), body, ErrTimestampExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.header, tt.body, DefaultTolerance, "secret"); err != tt.err {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("header format", func(t *testing.T) {
+		header := http.Header{}
+		(&Signing{Secrets: []string{"a", "b"}}).sign(header, "host", "1", body)
+		if signatures := strings.Split(header.Get(HeaderSignature), ","); len(signatures) != 2 {
+			t.Errorf("expected a signature per secret, got %v", signatures)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// reloadInterval is how often the files of a TLS configuration are checked for changes.
+var reloadInterval = time.Second
+
+// TLS configures the TLS connections of the REST transport, such as client certificates
+// for mutual TLS and a private CA. Certificate files are read when the first request is
+// sent and read again when they change on disk, so certificates can be renewed without
+// restarting. Connections opened before a reload keep their certificates until they are closed.
+type TLS struct {
+
+	// CertFile and KeyFile are the paths of the PEM-encoded client certificate and its key,
+	// presented to endpoints that request a client certificate. Both or none must be set.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is the path of the PEM-encoded CA certificates used to verify endpoints
+	// instead of the system roots.
+	CAFile string
+
+	// ServerName is the name used to verify the certificate of endpoints and sent as SNI,
+	// instead of the host of the endpoint.
+	ServerName string
+
+	// MinVersion is the minimum TLS version, such as tls.VersionTLS13.
+	// Default value: tls.VersionTLS12
+	MinVersion uint16
+}
+
+// files returns the paths of the files the configuration is read from.
+func (t *TLS) files() []string {
+	var files []string
+	for _, file := range []string{t.CertFile, t.KeyFile, t.CAFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// modified returns the latest modification time of the files of the configuration.
+func (t *TLS) modified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range t.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// config reads the files of the configuration and builds a tls.Config.
+func (t *TLS) config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: t.MinVersion,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no CA certificates found in " + t.CAFile)
+		}
+	}
+	return config, nil
+}
+
+// tlsClient is an HTTP client built from a TLS configuration.
+type tlsClient struct {
+	client   *http.Client
+	modified time.Time // Modification time of the files the client was built from.
+	checked  time.Time // When the files were last checked for changes.
+}
+
+// tlsFor returns the TLS configuration for host, nil for the default one.
+func (r *REST) tlsFor(host string) *TLS {
+	if t, ok := r.EndPointTLS[host]; ok {
+		return t
+	}
+	return r.TLS
+}
+
+// tlsClient returns the HTTP client for a TLS configuration, building it on first use and
+// rebuilding it once its files have changed. If the changed files cannot be read, for example
+// while they are being replaced, the previous client is kept and the files are checked again later.
+func (r *REST) tlsClient(t *TLS) (*http.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	current := r.clients[t]
+	if current != nil && now.Sub(current.checked) < reloadInterval {
+		return current.client, nil
+	}
+
+	modified, err := t.modified()
+	if current != nil && (err != nil || modified.Equal(current.modified)) {
+		current.checked = now
+		return current.client, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := t.config()
+	if err != nil {
+		if current != nil {
+			current.checked = now
+			return current.client, nil
+		}
+		return nil, err
+	}
+
+	client := r.HTTP.newClient(config)
+
+	// Stop reusing the connections made with the previous certificates.
+	if current != nil {
+		current.client.CloseIdleConnections()
+	}
+
+	if r.clients == nil {
+		r.clients = make(map[*TLS]*tlsClient)
+	}
+	r.clients[t] = &tlsClient{client: client, modified: modified, checked: now}
+	return r.clients[t].client, nil
+}
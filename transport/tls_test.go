@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM writes a PEM block of the given type to a new file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestTLSServer starts a TLS server answering "ok" and writes its certificate to a CA file.
+func newTestTLSServer(t *testing.T, config *tls.Config) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+}
+
+// TestTLS tests custom TLS configurations of the REST transport.
+func TestTLS(t *testing.T) {
+	msg := &Message{ID: "1", Data: []byte(`{"data": "test"}`)}
+	server, caFile := newTestTLSServer(t, nil)
+
+	t.Run("private CA", func(t *testing.T) {
+		if _, err := NewREST().Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected the unknown CA to be rejected")
+		}
+
+		rest := &REST{TLS: &TLS{CAFile: caFile}}
+		defer rest.Close()
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("server name", func(t *testing.T) {
+		// The certificate of the test server is valid for example.com but not for example.org.
+		rest := &REST{TLS: &TLS{CAFile: caFile, ServerName: "example.com"}}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		rest = &REST{TLS: &TLS{CAFile: caFile, ServerName: "example.org"}}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected the server name to be verified")
+		}
+	})
+
+	t.Run("min version", func(t *testing.T) {
+		old, oldCA := newTestTLSServer(t, &tls.Config{MaxVersion: tls.VersionTLS12})
+
+		rest := &REST{TLS: &TLS{CAFile: oldCA, MinVersion: tls.VersionTLS13}}
+		if _, err := rest.Send(context.Background(), old.URL, msg); err == nil {
+			t.Fatal("expected TLS 1.2 to be rejected")
+		}
+	})
+
+	t.Run("per endpoint", func(t *testing.T) {
+		rest := &REST{
+			TLS:         &TLS{CAFile: caFile, ServerName: "example.org"},
+			EndPointTLS: map[string]*TLS{server.URL: {CAFile: caFile}},
+		}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected the endpoint configuration to be used, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rest := &REST{TLS: &TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected an error for a missing CA file")
+		}
+	})
+}
+
+// TestTLS_Mutual tests presenting a client certificate and reloading it from disk.
+func TestTLS_Mutual(t *testing.T) {
+	msg := &Message{ID: "1", Data: []byte(`{"data": "test"}`)}
+
+	// The server only accepts the first of two client certificates.
+	trusted, pool := newTestCertificate(t)
+	untrusted, _ := newTestCertificate(t)
+	server, caFile := newTestTLSServer(t, &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool})
+
+	dir := t.TempDir()
+	writes := 0
+	writeCertificate := func(cert tls.Certificate) {
+		key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writePEM(t, dir, "client.pem", "CERTIFICATE", cert.Certificate[0])
+		writePEM(t, dir, "client.key", "PRIVATE KEY", key)
+
+		// Make sure the change is visible even on file systems with coarse modification times.
+		writes++
+		modified := time.Now().Add(time.Duration(writes) * time.Minute)
+		os.Chtimes(filepath.Join(dir, "client.pem"), modified, modified)
+	}
+
+	reload := reloadInterval
+	reloadInterval = 0
+	defer func() { reloadInterval = reload }()
+
+	rest := &REST{TLS: &TLS{
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+		CAFile:   caFile,
+	}}
+	defer rest.Close()
+
+	t.Run("without certificate", func(t *testing.T) {
+		rest := &REST{TLS: &TLS{CAFile: caFile}}
+		if _, err := rest.Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected a request without client certificate to be rejected")
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		writeCertificate(untrusted)
+		if _, err := rest.Send(context.Background(), server.URL, msg); err == nil {
+			t.Fatal("expected the untrusted client certificate to be rejected")
+		}
+	})
+
+	t.Run("reloaded certificate", func(t *testing.T) {
+		writeCertificate(trusted)
+		if _, err := rest.Send(context.Background(), server.URL, msg); err != nil {
+			t.Fatalf("expected the reloaded client certificate to be accepted, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package transport
+
+import "context"
+
+// HeaderMessageID is the header carrying the message ID, so that receivers can
+// correlate and deduplicate deliveries.
+const HeaderMessageID = "X-Message-Id"
+
+// Message is a payload as handed to a Transport.
+type Message struct {
+	// ID uniquely identifies the emitted message. Retries of a message share its ID.
+	ID string `json:"id,omitempty"`
+
+	// Header holds metadata of the message, such as tenant, event type or trace IDs.
+	// REST sends it as HTTP headers, QUIC as part of the stream metadata.
+	Header map[string]string `json:"header,omitempty"`
+
+	// Data is the payload of the message.
+	Data []byte `json:"-"`
+}
+
+// Transport delivers messages to endpoints. Implementations must be safe for
+// concurrent use, as every worker of a callback sends through the same Transport.
+type Transport interface {
+	// Send delivers msg to endpoint and returns the response body.
+	// A non-nil error marks the delivery as failed.
+	Send(ctx context.Context, endpoint string, msg *Message) ([]byte, error)
+
+	// Close releases the resources held by the transport, such as pooled connections.
+	Close() error
+}
@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard Webhooks headers, see https://www.standardwebhooks.com.
+const (
+	// HeaderWebhookID is the header carrying the message ID.
+	HeaderWebhookID = "webhook-id"
+
+	// HeaderWebhookTimestamp is the header carrying the Unix time in seconds at which a request was signed.
+	HeaderWebhookTimestamp = "webhook-timestamp"
+
+	// HeaderWebhookSignature is the header carrying the space-delimited "v1,<base64>" signatures.
+	HeaderWebhookSignature = "webhook-signature"
+
+	// webhookSecretPrefix is the prefix of Standard Webhooks secrets.
+	webhookSecretPrefix = "whsec_"
+)
+
+// ErrInvalidSecret is returned for a Standard Webhooks secret that is not "whsec_" followed by base64.
+var ErrInvalidSecret = errors.New("invalid webhook secret")
+
+// Scheme defines the format of the signature headers of a signed request.
+type Scheme string
+
+var (
+	// CallbackScheme signs requests with HeaderTimestamp and HeaderSignature. See Verify.
+	CallbackScheme Scheme = "callback"
+
+	// StandardWebhooks signs requests as defined by the Standard Webhooks specification:
+	// the message ID, timestamp and signatures are sent in the webhook-id, webhook-timestamp
+	// and webhook-signature headers, and secrets are given as "whsec_<base64>".
+	// Receivers can verify requests with VerifyWebhook or any Standard Webhooks library.
+	StandardWebhooks Scheme = "standard_webhooks"
+)
+
+// signWebhook sets the Standard Webhooks headers of a request with the given message ID and body.
+func signWebhook(header http.Header, secrets []string, id string, body []byte) error {
+	if id == "" {
+		return errors.New("standard webhooks require a message ID")
+	}
+
+	timestamp := time.Now().Unix()
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signature, err := SignWebhook(secret, id, timestamp, body)
+		if err != nil {
+			return err
+		}
+		signatures = append(signatures, "v1,"+signature)
+	}
+
+	header.Set(HeaderWebhookID, id)
+	header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderWebhookSignature, strings.Join(signatures, " "))
+	return nil
+}
+
+// SignWebhook returns the base64-encoded HMAC-SHA256 of "<id>.<timestamp>.<body>"
+// with a "whsec_" secret, as defined by the Standard Webhooks specification.
+func SignWebhook(secret, id string, timestamp int64, body []byte) (string, error) {
+	key, err := webhookKey(secret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// webhookKey decodes the signing key of a "whsec_" secret.
+func webhookKey(secret string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(secret, webhookSecretPrefix)
+	if !ok {
+		return nil, ErrInvalidSecret
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// VerifyWebhook checks the Standard Webhooks signature of a received request with the given
+// headers and body. It succeeds if any signature of the request matches any of the secrets
+// and the timestamp is within tolerance of the current time. Use DefaultTolerance unless
+// there is a reason not to. The webhook-id header can be used to deduplicate deliveries.
+func VerifyWebhook(header http.Header, body []byte, tolerance time.Duration, secrets ...string) error {
+	id := header.Get(HeaderWebhookID)
+	value := header.Get(HeaderWebhookTimestamp)
+	signatures := header.Get(HeaderWebhookSignature)
+	if id == "" || value == "" || signatures == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestampExpired
+	}
+
+	for _, secret := range secrets {
+		expected, err := SignWebhook(secret, id, timestamp, body)
+		if err != nil {
+			return err
+		}
+		for _, signature := range strings.Fields(signatures) {
+			version, value, ok := strings.Cut(signature, ",")
+			if ok && version == "v1" && hmac.Equal([]byte(value), []byte(expected)) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
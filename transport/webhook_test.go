@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSignWebhook tests the signature against the example of the Standard Webhooks specification.
+func TestSignWebhook(t *testing.T) {
+	signature, err := SignWebhook("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", "msg_p5jXN8AQM9LWM0D4loKWxJek", 1614265330, []byte(`{"test": 2432232314}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := "g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="; signature != expected {
+		t.Errorf("expected %s, got %s", expected, signature)
+	}
+
+	for _, secret := range []string{"MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", "whsec_!!!", "whsec_"} {
+		if _, err := SignWebhook(secret, "1", 0, nil); err != ErrInvalidSecret {
+			t.Errorf("expected ErrInvalidSecret for %q, got %v", secret, err)
+		}
+	}
+}
+
+// TestStandardWebhooks tests sending requests in the Standard Webhooks format.
+func TestStandardWebhooks(t *testing.T) {
+	const secret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhook(r.Header, body, DefaultTolerance, secret); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	rest := &REST{Signing: &Signing{Scheme: StandardWebhooks, Secrets: []string{"whsec_b2xk", secret}}}
+
+	t.Run("headers", func(t *testing.T) {
+		if _, err := rest.Send(context.Background(), server.URL, &Message{ID: "msg_1", Data: []byte("{}")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if id := header.Get(HeaderWebhookID); id != "msg_1" {
+			t.Errorf("expected the message ID in webhook-id, got %q", id)
+		}
+		if header.Get(HeaderSignature) != "" {
+			t.Error("expected no callback signature header")
+		}
+	})
+
+	t.Run("missing ID", func(t *testing.T) {
+		if _, err := rest.Send(context.Background(), server.URL, &Message{Data: []byte("{}")}); err == nil {
+			t.Fatal("expected an error for a message without ID")
+		}
+	})
+}
+
+// TestVerifyWebhook tests the rejection of invalid Standard Webhooks signatures.
+func TestVerifyWebhook(t *testing.T) {
+	const secret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	body := []byte("payload")
+	signed := func(id string, timestamp int64) http.Header {
+		signature, _ := SignWebhook(secret, id, timestamp, body)
+		header := http.Header{}
+		header.Set(HeaderWebhookID, id)
+		header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+		header.Set(HeaderWebhookSignature, "v1,invalid v1,"+signature)
+		return header
+	}
+	now := time.Now().Unix()
+
+	if err := VerifyWebhook(signed("msg_1", now), body, DefaultTolerance, secret); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+
+	t.Run("changed ID", func(t *testing.T) {
+		header := signed("msg_1", now)
+		header.Set(HeaderWebhookID, "msg_2")
+		if err := VerifyWebhook(header, body, DefaultTolerance, secret); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		if err := VerifyWebhook(signed("msg_1", now-3600), body, DefaultTolerance, secret); err != ErrTimestampExpired {
+			t.Errorf("expected ErrTimestampExpired, got %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if err := VerifyWebhook(http.Header{}, body, DefaultTolerance, secret); err != ErrMissingSignature {
+			t.Errorf("expected ErrMissingSignature, got %v", err)
+		}
+	})
+}
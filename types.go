@@ -1,10 +1,52 @@
 package callback
 
+import "time"
+
 type Data struct {
-	Point    string    `json:"point"`
-	Success  bool      `json:"success"`
-	Response *Response `json:"response"`
-	Error    *Error    `json:"error"`
+	ID       string            `json:"id"`
+	Header   map[string]string `json:"header,omitempty"`
+	Point    string            `json:"point"`
+	Success  bool              `json:"success"`
+	Response *Response         `json:"response"`
+	Error    *Error            `json:"error"`
+	Summary  *Summary          `json:"summary,omitempty"`
+	Attempts []Attempt         `json:"attempts,omitempty"`
+}
+
+// Attempt describes a single attempt to deliver a message to an endpoint.
+type Attempt struct {
+	Point string `json:"point"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// Summary is the aggregate result of a delivery to several endpoints.
+type Summary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	Pending   int `json:"pending,omitempty"`
+	Quorum    int `json:"quorum,omitempty"`
+}
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+var (
+	// BreakerEvent reports a circuit breaker state transition of an endpoint.
+	BreakerEvent EventType = "breaker"
+
+	// HealthEvent reports an endpoint becoming healthy or unhealthy by active health checks.
+	HealthEvent EventType = "health"
+)
+
+// Event reports a state transition of an endpoint.
+type Event struct {
+	Point string    `json:"point"`
+	Type  EventType `json:"type"`
+	From  State     `json:"from"`
+	To    State     `json:"to"`
+	Time  time.Time `json:"time"`
 }
 
 type ErrorInterface interface {
@@ -21,6 +63,11 @@ func (r *Error) IsError() bool {
 	return true
 }
 
+// Error implements the error interface, so an *Error can be returned by Request.
+func (r *Error) Error() string {
+	return r.Message
+}
+
 type ResponseInterface interface {
 	IsResponse() bool
 }
@@ -0,0 +1,83 @@
+package callback
+
+// weightedRoundRobin sends the message to the next available worker chosen by
+// smooth weighted round-robin. If all workers are blocked, it returns an error.
+func (c *Callback) weightedRoundRobin(msg *message) error {
+	return c.enqueueNext(msg, c.nextWeighted)
+}
+
+// nextWeighted picks the next available worker using the smooth weighted round-robin
+// algorithm known from nginx: on every pick each available worker's current weight grows
+// by its weight, the worker with the highest current weight is chosen and its current
+// weight is lowered by the total weight. Picks are spread evenly over the cycle instead of
+// sending bursts to the heaviest worker. Blocked workers and workers with a zero weight are
+// left out, so their share is redistributed among the others. It returns nil if no worker is available.
+func (c *Callback) nextWeighted() *Worker {
+	workers := c.workers()
+
+	c.balanceMu.Lock()
+	defer c.balanceMu.Unlock()
+
+	var best *Worker
+	total := 0
+	for _, worker := range workers {
+		if worker.weight <= 0 || !worker.available() {
+			continue
+		}
+
+		worker.currentWeight += worker.weight
+		total += worker.weight
+		if best == nil || worker.currentWeight > best.currentWeight {
+			best = worker
+		}
+	}
+
+	if best != nil {
+		best.currentWeight -= total
+	}
+	return best
+}
+
+// weightOf returns the configured weight of an endpoint, 1 if none is configured.
+func (c *Callback) weightOf(host string) int {
+	if weight, ok := c.weights[host]; ok {
+		return weight
+	}
+	return 1
+}
+
+// setWeight changes the weight of a worker and restarts its smooth round-robin state.
+func (c *Callback) setWeight(worker *Worker, weight int) {
+	c.balanceMu.Lock()
+	defer c.balanceMu.Unlock()
+
+	worker.weight = weight
+	worker.currentWeight = 0
+}
+
+// SyncWeightedEndPoint synchronizes the endpoints with the keys of weights like SyncEndPoint
+// and sets the weight of every endpoint, including existing ones.
+func (c *Callback) SyncWeightedEndPoint(weights map[string]int) {
+	hosts := make([]string, 0, len(weights))
+	for host := range weights {
+		hosts = append(hosts, host)
+	}
+
+	c.mu.Lock()
+	if c.weights == nil {
+		c.weights = make(map[string]int, len(weights))
+	}
+	for host, weight := range weights {
+		c.weights[host] = weight
+	}
+	c.mu.Unlock()
+
+	c.SyncEndPoint(hosts)
+
+	// Apply the weights to workers that already existed.
+	for _, worker := range c.workers() {
+		if weight, ok := weights[worker.point]; ok {
+			c.setWeight(worker, weight)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weightedPicks returns the endpoints chosen by n consecutive weighted picks.
+func weightedPicks(callback *Callback, n int) string {
+	picks := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		worker := callback.nextWeighted()
+		if worker == nil {
+			picks = append(picks, "-")
+			continue
+		}
+		picks = append(picks, worker.point)
+	}
+	return strings.Join(picks, " ")
+}
+
+// TestWeighted tests the smooth weighted round-robin selection of workers.
+func TestWeighted(t *testing.T) {
+	t.Run("smooth order", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", weight: 5},
+			{point: "b", weight: 1},
+			{point: "c", weight: 1},
+		}}
+
+		// The nginx sequence for weights 5, 1, 1 spreads the picks of the heavy endpoint.
+		if got := weightedPicks(callback, 7); got != "a a b a c a a" {
+			t.Errorf("expected picks 'a a b a c a a', got '%s'", got)
+		}
+	})
+
+	t.Run("blocked worker", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", weight: 2},
+			{point: "b", weight: 1},
+			{point: "c", weight: 1, blockedUntil: time.Now().Add(time.Minute)},
+		}}
+
+		// The share of the blocked worker is redistributed among the others.
+		if got := weightedPicks(callback, 6); got != "a b a a b a" {
+			t.Errorf("expected picks 'a b a a b a', got '%s'", got)
+		}
+	})
+
+	t.Run("zero weight", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", weight: 0},
+			{point: "b", weight: 1},
+		}}
+
+		if got := weightedPicks(callback, 3); got != "b b b" {
+			t.Errorf("expected picks 'b b b', got '%s'", got)
+		}
+	})
+
+	t.Run("all blocked", func(t *testing.T) {
+		callback := &Callback{endPoints: []*Worker{
+			{point: "a", weight: 1, messageQueue: make(chan *message, 1), blockedUntil: time.Now().Add(time.Minute)},
+		}}
+
+		err := callback.weightedRoundRobin(newMessage(context.Background(), []byte("test data")))
+		if !errors.Is(err, ErrAllBlocked) {
+			t.Errorf("expected ErrAllBlocked, got %v", err)
+		}
+	})
+}
+
+// TestWeighted_Runtime tests changing the weights of endpoints at runtime.
+func TestWeighted_Runtime(t *testing.T) {
+	weights := map[string]int{"a": 3}
+	clb := New(&Options{
+		Transport:    &testTransport{},
+		DeliveryMode: WeightedRoundRobin,
+		EndPoints:    []string{"a", "b"},
+		Weights:      weights,
+	})
+	defer clb.Close()
+
+	t.Run("options", func(t *testing.T) {
+		if got := weightedPicks(clb, 4); got != "a a b a" {
+			t.Errorf("expected picks 'a a b a', got '%s'", got)
+		}
+	})
+
+	t.Run("add endpoint", func(t *testing.T) {
+		// Change the weight of an existing endpoint and add a new weighted one.
+		clb.AddEndpoint("a", 1)
+		clb.AddEndpoint("c", 2)
+
+		if got := weightedPicks(clb, 4); got != "c a b c" {
+			t.Errorf("expected picks 'c a b c', got '%s'", got)
+		}
+	})
+
+	t.Run("sync endpoints", func(t *testing.T) {
+		// Existing endpoints keep their weight.
+		clb.SyncEndPoint([]string{"b", "c"})
+		if got := weightedPicks(clb, 3); got != "c b c" {
+			t.Errorf("expected picks 'c b c', got '%s'", got)
+		}
+
+		clb.SyncWeightedEndPoint(map[string]int{"b": 2, "d": 1})
+		if got := weightedPicks(clb, 3); got != "b d b" {
+			t.Errorf("expected picks 'b d b', got '%s'", got)
+		}
+	})
+
+	t.Run("options unchanged", func(t *testing.T) {
+		if len(weights) != 1 || weights["a"] != 3 {
+			t.Errorf("expected the weights in Options to be left unchanged, got %v", weights)
+		}
+	})
+}
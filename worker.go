@@ -1,14 +1,64 @@
 package callback
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gmelum/callback/transport"
 )
 
+// message is a single payload queued for delivery by a worker.
+type message struct {
+
+	// The context of the emission, bounding the queueing and the transport requests.
+	ctx context.Context
+
+	// The ID of the message, shared by all attempts and endpoints.
+	id string
+
+	// The metadata of the message.
+	header map[string]string
+
+	// The partition key of the message, empty if none.
+	key string
+
+	// The payload to send to the endpoint.
+	data []byte
+
+	// The broadcast group the message belongs to, nil for single deliveries.
+	group *group
+
+	// The attempts made so far to deliver the message.
+	attempts []Attempt
+
+	// The delay before the latest retry, used by the backoff policy.
+	delay time.Duration
+
+	// Whether the message is a probe sent to a half-open worker.
+	probe bool
+
+	// A channel receiving the final result of the message, nil unless sent with Request.
+	// For a broadcast group, it receives the aggregate result.
+	reply chan Data
+}
+
+// newMessage creates a message with a new ID for data emitted with ctx.
+func newMessage(ctx context.Context, data []byte) *message {
+	return &message{ctx: ctx, id: newID(), data: data}
+}
+
+// answer sends the final result to the reply channel if the message was sent with Request.
+// The channel is buffered and receives a single result, so this never blocks.
+func (m *message) answer(data Data) {
+	if m.reply != nil {
+		m.reply <- data
+	}
+}
+
 // Worker represents a process that handles incoming data and interacts with an external callback interface.
 type Worker struct {
 
@@ -19,7 +69,7 @@ type Worker struct {
 	point string
 
 	// A channel for receiving messages to process.
-	messageQueue chan []byte
+	messageQueue chan *message
 
 	// A channel to return the result (Response or Error) after processing.
 	returnChannel chan Data
@@ -33,8 +83,46 @@ type Worker struct {
 	// The time until which the worker will be blocked if retry limits are exceeded.
 	blockedUntil time.Time
 
+	// The number of consecutive times the worker has been blocked, used by the backoff policy.
+	blocks int
+
+	// The duration of the latest block period.
+	blockedFor time.Duration
+
+	// The circuit breaker state of the worker.
+	state State
+
+	// The number of probe messages in flight while the worker is half-open.
+	probes int
+
+	// The number of consecutive successful probes while the worker is half-open.
+	successes int
+
+	// State transitions waiting to be reported.
+	transitions []Event
+
+	// Whether the endpoint failed its active health checks and is out of rotation.
+	unhealthy bool
+
+	// The number of consecutive passed and failed health checks.
+	checkPasses, checkFailures int
+
+	// The time and error of the latest health check.
+	checked    time.Time
+	checkError string
+
 	// A channel for stopping the worker.
 	stop chan struct{}
+
+	// The weight of the worker and its current weight in WeightedRoundRobin mode,
+	// guarded by the callback's balanceMu.
+	weight, currentWeight int
+
+	// The priority tier of the worker in Failover mode, 0 being the primary.
+	priority int
+
+	// The number of messages queued on the worker or being processed by it.
+	outstanding atomic.Int64
 }
 
 // NewWorker creates a new Worker object and starts the necessary goroutines for processing data and handling responses.
@@ -49,17 +137,34 @@ func NewWorker(c *Callback, point string) *Worker {
 		point: point,
 
 		// A buffered channel for message queue with a size of 2.
-		messageQueue: make(chan []byte, 100),
+		messageQueue: make(chan *message, 100),
 
 		// Set the returnChannel from the callback.
 		returnChannel: c.returnChannel,
 
 		// Initialize the error timestamps with a maximum capacity.
 		errorTimestamps: make([]time.Time, 0, c.retryLimit),
+
+		// Initialize the stop channel used by Close.
+		stop: make(chan struct{}),
+
+		// A new worker starts with a closed circuit breaker.
+		state: Closed,
+
+		// Set the weight configured for the endpoint.
+		weight: c.weightOf(point),
+
+		// Set the priority tier configured for the endpoint.
+		priority: c.priorityOf(point),
 	}
 
 	// Start another goroutine for processing incoming messages.
 	go worker.handler()
+
+	// Start active health checking of the endpoint if configured.
+	if c.healthCheck != nil {
+		go worker.healthCheck()
+	}
 	return worker
 }
 
@@ -85,33 +190,211 @@ func (w *Worker) handler() {
 
 	for {
 		select {
-		case data := <-w.messageQueue: // If a message is received from the messageQueue.
-			// Process the message.
-			res, err := w.handlerRequest(data)
-			if err != nil { // If an error occurs while processing.
-				// Increment the error count and send an error response.
-				w.Inc()
-				w.returnChannel <- w.sendReturn(
-					&Error{
-						Code:     0,
-						Message:  fmt.Sprintf("[ERROR] %v", err.Error()), // Format the error message.
-						Critical: true,
-					},
-				)
-				continue // Continue processing the next message.
-			}
-
-			// If the processing succeeds, reset error counters and return the successful result.
-			w.Reset()
-			w.returnChannel <- w.sendReturn(&Response{res}) // Send the successful response.
+		case msg := <-w.messageQueue: // If a message is received from the messageQueue.
+			w.process(msg) // Process the message.
 
 		case <-w.stop: // If the stop signal is received.
-			return // Exit the handler goroutine.
+			w.drain() // Fail the messages still waiting in the queue.
+			return    // Exit the handler goroutine.
 		}
 	}
 
 }
 
+// drain fails every message left in the queue of a closed worker, so that pending
+// Requests are answered and the next messages of their keys are let go in Ordered mode.
+func (w *Worker) drain() {
+	for {
+		select {
+		case msg := <-w.messageQueue:
+			w.outstanding.Add(-1)
+			w.release(msg)
+			w.deliver(msg, w.sendReturn(&Error{
+				Code:     0,
+				Message:  "[ERROR] endpoint was removed before the message was sent",
+				Critical: true,
+			}))
+		default:
+			return
+		}
+	}
+}
+
+// closed reports whether the worker has been closed.
+func (w *Worker) closed() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// queued must be called after a message was added to the queue. If the worker was
+// closed meanwhile, its handler may already have drained the queue, so it is drained here.
+func (w *Worker) queued() {
+	if w.closed() {
+		w.drain()
+	}
+}
+
+// process sends the message to the worker's endpoint. A failed attempt is retried
+// according to the retry mode until the retry limit is reached: Repeat retries on this
+// worker, Next hands the message to the next available worker chosen by the delivery mode.
+func (w *Worker) process(msg *message) {
+	// The message stops being outstanding once it leaves this worker.
+	defer w.outstanding.Add(-1)
+
+	for {
+		// A message whose context is done is reported as failed without being sent.
+		// This is not the endpoint's fault, so it is neither counted nor retried.
+		if err := msg.ctx.Err(); err != nil {
+			w.release(msg)
+			w.deliver(msg, w.sendReturn(&Error{
+				Code:     0,
+				Message:  fmt.Sprintf("[ERROR] %v", err.Error()),
+				Critical: true,
+			}))
+			return
+		}
+
+		winner, res, err := w.request(msg)
+		w.release(msg)
+		if err == nil {
+			// If the processing succeeds, record the success and return the successful result.
+			// A hedged message may have succeeded on another worker.
+			msg.attempts = append(msg.attempts, Attempt{Point: winner.point})
+			winner.succeed()
+			winner.deliver(msg, winner.sendReturn(&Response{res}))
+			return
+		}
+
+		// Increment the error count and record the failed attempt.
+		blocked := w.Inc()
+		failure := &Error{
+			Code:     0,
+			Message:  fmt.Sprintf("[ERROR] %v", err.Error()), // Format the error message.
+			Critical: true,
+		}
+		msg.attempts = append(msg.attempts, Attempt{Point: w.point, Error: failure})
+
+		// Report the error once the message has used up its retries.
+		if len(msg.attempts) > w.callback.retryLimit {
+			w.deliver(msg, w.sendReturn(failure))
+			return
+		}
+
+		// Wait before the next attempt if a backoff policy is configured.
+		delay := w.callback.retryDelay(msg)
+
+		// In Next mode, hand the message to the next available worker. Messages of a
+		// broadcast group are bound to their endpoint and are always repeated in place.
+		if w.callback.retryMode == Next && msg.group == nil {
+			after(delay, func() { w.retryNext(msg, failure) })
+			return
+		}
+
+		// Repeat on this worker unless its endpoint got blocked.
+		if blocked {
+			w.deliver(msg, w.sendReturn(failure))
+			return
+		}
+
+		// Requeue the message after the delay so other messages are not held up meanwhile.
+		if delay > 0 {
+			time.AfterFunc(delay, func() {
+				w.track(msg)
+				w.forward(msg)
+			})
+			return
+		}
+	}
+}
+
+// retryNext hands a failed message to the next available worker chosen by the delivery mode,
+// which may be this worker again. If all workers are blocked, the failure is reported.
+func (w *Worker) retryNext(msg *message, failure *Error) {
+	if next := w.callback.reserveNext(msg); next != nil {
+		next.forward(msg)
+		return
+	}
+	w.deliver(msg, w.sendReturn(failure))
+}
+
+// errUnavailable is returned by enqueue when the worker stopped accepting messages
+// after it was chosen.
+var errUnavailable = errors.New("endpoint is no longer available")
+
+// enqueue adds a message to the queue, waiting for room until the message context is done
+// or the worker is closed. It returns errUnavailable if the worker no longer accepts
+// messages, for example because the probe slots of a half-open worker were taken meanwhile.
+func (w *Worker) enqueue(msg *message) error {
+	if !w.reserve(&msg.probe) {
+		return errUnavailable
+	}
+	return w.push(msg)
+}
+
+// push adds a message reserved by the worker to the queue like enqueue.
+// If the message is not queued, its probe slot is freed.
+func (w *Worker) push(msg *message) error {
+	w.outstanding.Add(1)
+
+	select {
+	case w.messageQueue <- msg:
+		w.queued()
+		return nil
+	case <-msg.ctx.Done():
+		w.outstanding.Add(-1)
+		w.release(msg)
+		return msg.ctx.Err()
+	case <-w.stop:
+		w.outstanding.Add(-1)
+		w.release(msg)
+		return errors.New("endpoint was removed before the message was queued")
+	}
+}
+
+// forward enqueues a message handed over for a retry, which must already be tracked by the worker.
+// It never blocks the caller: if the queue is full, the message is enqueued in the background.
+func (w *Worker) forward(msg *message) {
+	// The worker may have been closed while the retry was waiting.
+	select {
+	case <-w.stop:
+		w.release(msg)
+		w.deliver(msg, w.sendReturn(&Error{
+			Code:     0,
+			Message:  "[ERROR] endpoint was removed before the retry",
+			Critical: true,
+		}))
+		return
+	default:
+	}
+
+	w.outstanding.Add(1)
+
+	select {
+	case w.messageQueue <- msg:
+		w.queued()
+	default:
+		go func() {
+			select {
+			case w.messageQueue <- msg:
+				w.queued()
+			case <-w.stop:
+				// The worker was closed before it could accept the message.
+				w.outstanding.Add(-1)
+				w.release(msg)
+				w.deliver(msg, w.sendReturn(&Error{
+					Code:     0,
+					Message:  "[ERROR] endpoint was removed before the retry",
+					Critical: true,
+				}))
+			}
+		}()
+	}
+}
+
 // sendReturn formats and sends the result (Response or Error) to the returnChannel.
 func (w *Worker) sendReturn(result interface{}) Data {
 	var success bool
@@ -144,24 +427,65 @@ func (w *Worker) sendReturn(result interface{}) Data {
 	}
 }
 
-// handlerRequest processes incoming data. Currently a stub, needs to be implemented with specific logic.
-func (w *Worker) handlerRequest(data []byte) ([]byte, error) {
+// deliver finishes the message with its result, which reports it to the returnChannel.
+// For broadcast messages it sends the result of the endpoint to the returnChannel, records
+// it in the group and finishes the message with the aggregate result once complete.
+func (w *Worker) deliver(msg *message, data Data) {
+	// Report the message ID, metadata and every attempt made for the message.
+	data.ID = msg.id
+	data.Header = msg.header
+	data.Attempts = msg.attempts
+
+	// A single delivery is final right away.
+	if msg.group == nil {
+		w.callback.finish(msg, data)
+		return
+	}
+
+	w.returnChannel <- data
 
-	if w.callback.transport == REST {
-		return transport.Post(w.point, data)
+	// Report the aggregate result after the last endpoint of the broadcast has answered.
+	if summary, ok := msg.group.add(&data); ok {
+		summary.ID = msg.id
+		summary.Header = msg.header
+		w.callback.finish(msg, summary)
 	}
+}
 
-	// TODO: Implement the logic to handle the incoming data (e.g., process the byte slice).
-	return nil, errors.New("transport is not support")
+// handlerRequest sends the message to the worker's endpoint through the callback transport.
+func (w *Worker) handlerRequest(ctx context.Context, msg *message) ([]byte, error) {
+	return w.callback.transport.Send(ctx, w.point, &transport.Message{
+		ID:     msg.id,
+		Header: msg.header,
+		Data:   msg.data,
+	})
 }
 
 // Inc increments the error count and checks if the worker should be blocked due to too many errors.
+// A failure while half-open, or after the block period of an open worker has passed,
+// opens the circuit breaker again for a longer period.
 func (w *Worker) Inc() bool {
+	defer w.flush() // Report state transitions once the mutex is released.
+
 	w.mu.Lock()         // Lock for thread-safe access to shared resources.
 	defer w.mu.Unlock() // Ensure the mutex is released when the method finishes.
 
+	now := time.Now() // Get the current time.
+
+	switch w.state {
+	case HalfOpen:
+		// A failed probe opens the breaker again.
+		w.open(now)
+		return true
+	case Open:
+		// If the worker is not currently blocked, set the block time.
+		if now.After(w.blockedUntil) {
+			w.open(now)
+		}
+		return true
+	}
+
 	// Remove errors that are outside of the retry window.
-	now := time.Now()                               // Get the current time.
 	windowStart := now.Add(-w.callback.retryWindow) // The start of the retry window.
 	filteredErrors := w.errorTimestamps[:0]         // Create a new slice to hold only recent errors.
 
@@ -176,12 +500,9 @@ func (w *Worker) Inc() bool {
 	// Add the current error timestamp.
 	w.errorTimestamps = append(w.errorTimestamps, now)
 
-	// If the number of errors exceeds the retry limit, check if the worker should be blocked.
+	// If the number of errors exceeds the retry limit, open the breaker.
 	if len(w.errorTimestamps) > w.callback.retryLimit {
-		// If the worker is not currently blocked, set the block time.
-		if now.After(w.blockedUntil) {
-			w.blockedUntil = now.Add(w.callback.retryTimeout) // Set the blockedUntil time to retryTimeout after the current time.
-		}
+		w.open(now)
 		return true // Indicate that the worker is blocked due to too many errors.
 	}
 
@@ -189,17 +510,60 @@ func (w *Worker) Inc() bool {
 	return now.Before(w.blockedUntil) // Return whether the worker is still within the blocked period.
 }
 
-// Reset clears the error count and unblocks the worker.
+// available reports whether the worker can accept messages. Unhealthy workers are unavailable.
+// An open worker whose block period has passed becomes half-open and then accepts
+// up to HalfOpenProbes probe messages at a time.
+func (w *Worker) available() bool {
+	defer w.flush() // Report state transitions once the mutex is released.
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.accepts(time.Now())
+}
+
+// accepts reports whether the worker can accept messages at now. It must be called with w.mu held.
+func (w *Worker) accepts(now time.Time) bool {
+	if w.unhealthy || !now.After(w.blockedUntil) {
+		return false
+	}
+
+	w.halfOpen(now)
+	if w.state == HalfOpen {
+		return w.probes < w.callback.halfOpenProbes
+	}
+	return true
+}
+
+// Reset clears the error count and unblocks the worker, closing its circuit breaker.
 func (w *Worker) Reset() {
+	defer w.flush() // Report state transitions once the mutex is released.
+
 	w.mu.Lock()         // Lock for thread-safe modification of the state.
 	defer w.mu.Unlock() // Ensure the mutex is released when the method finishes.
 
-	// Clear the list of error timestamps and reset the blockedUntil time.
-	w.errorTimestamps = w.errorTimestamps[:0]
-	w.blockedUntil = time.Time{} // Reset the blockedUntil time to zero.
+	w.close()
+}
+
+// State returns the circuit breaker state of the worker.
+func (w *Worker) State() State {
+	defer w.flush() // Report state transitions once the mutex is released.
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.halfOpen(time.Now())
+	if w.state == "" {
+		return Closed
+	}
+	return w.state
 }
 
 // Close stops the worker by closing the stop channel, signaling all goroutines to terminate.
+// Queued messages are failed and reported before it returns, so it must not be called with c.mu held.
 func (w *Worker) Close() {
 	close(w.stop) // Close the stop channel to signal worker termination.
+
+	// Fail the queued messages now rather than after the message in flight, if any, completes.
+	w.drain()
 }